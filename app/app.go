@@ -29,6 +29,14 @@ func Start() {
 	// Set global tracer
 	opentracing.SetGlobalTracer(tracer)
 
+	if err := utils.InitKeySet(cfg); err != nil {
+		logrus.Fatalf("Failed to load signing keys: %v", err)
+	}
+
+	if err := utils.InitPasswordHasher(cfg); err != nil {
+		logrus.Fatalf("Failed to load password settings: %v", err)
+	}
+
 	connection.InitConnection(*cfg)
 	router.InitFactory(cfg, connection.Db, connection.Storage, connection.Redis, connection.Mq)
 
@@ -37,6 +45,11 @@ func Start() {
 
 	e := echo.New()
 
+	e.IPExtractor, err = utils.NewIPExtractor(cfg)
+	if err != nil {
+		logrus.Fatalf("Failed to load trusted proxies: %v", err)
+	}
+
 	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
 		LogErrorFunc: utils.LogError,
 	}))
@@ -51,20 +64,35 @@ func Start() {
 		NewClaimsFunc: func(c echo.Context) jwt.Claims {
 			return new(model.JwtCustomClaims)
 		},
-		SigningKey: []byte(cfg.Auth.AccessSecret),
+		KeyFunc: utils.Keys.Keyfunc,
+		Skipper: func(c echo.Context) bool {
+			// Already authenticated with an API key.
+			return c.Get("user") != nil
+		},
 	}
 
 	public := e.Group("api")
-	api := public.Group("/service")
+	session := public.Group("/service")
+
+	session.Use(utils.IsApiKeyValid(router.ApiKeyAuthenticator()))
+	session.Use(echojwt.WithConfig(auth))
+	session.Use(utils.IsTokenActive(connection.Redis))
+	session.Use(utils.IsTenantScoped(router.TenantResolver()))
+	session.Use(utils.IsImpersonationAllowed(router.ImpersonationAuditor(), "/api/service/user/logout", "/api/service/me", "/api/service/user/logout/all"))
 
-	api.Use(echojwt.WithConfig(auth))
-	api.Use(utils.IsAuthorized())
+	api := session.Group("")
+	api.Use(utils.IsAuthorized("/api/service", router.Authorizer()))
 
 	e.Use(middleware.Logger())
 	router.InitPublicRoute("", public)
+	router.InitSessionRoute("/user", session)
+	router.InitProfileRoute("/me", session)
 	router.InitUserRoute("/user", api)
 	router.InitRoleRoute("/role", api)
 	router.InitParamRoute("/param", api)
+	router.InitServiceAccountRoute("/service-account", api)
+	router.InitImpersonationRoute("/impersonation", api)
+	router.InitInstitutionRoute("/institution", api)
 
 	e.Logger.Fatal(e.Start(host + ":" + strconv.Itoa(port)))
 }
@@ -0,0 +1,14 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/app/model"
+)
+
+// InterfaceAuthenticator checks passwords against an external account store. Users are
+// routed to an authenticator by their auth_source.
+type InterfaceAuthenticator interface {
+	// Authenticate returns the verified account, a 401 error for a wrong password and a
+	// 404 error for an unknown username. Any other error means the store is unavailable.
+	Authenticate(ctx context.Context, username string, password string) (*model.ExternalUser, error)
+}
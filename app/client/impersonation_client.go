@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceImpersonationClient interface {
+	CreateImpersonationAudit(ctx context.Context, audit *model.ImpersonationAudit) error
+	GetImpersonationAudit(ctx context.Context, filter *model.ImpersonationAuditFilter) ([]*model.ImpersonationAudit, int64, error)
+}
+
+type ImpersonationClient struct {
+	db *gorm.DB
+}
+
+func NewImpersonationClient(db *gorm.DB) *ImpersonationClient {
+	return &ImpersonationClient{db: db}
+}
+
+// CreateImpersonationAudit records an impersonation event. When the institution is not known
+// it is taken from the impersonated user.
+func (c *ImpersonationClient) CreateImpersonationAudit(ctx context.Context, req *model.ImpersonationAudit) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateImpersonationAudit")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.Actor, req.Username, req.InstitutionID, req.Username, req.SessionID, req.Method, req.Path, req.Status, req.Blocked, req.Reason, req.IPAddress, req.CreatedAt)
+
+	query := "INSERT INTO impersonation_audit (actor, username, institution_id, session_id, method, path, status, blocked, reason, ip_address, created_at) VALUES (?, ?, COALESCE(NULLIF(?, ''), (SELECT institution_id FROM users WHERE username = ?)), ?, ?, ?, ?, ?, ?, ?, ?)"
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Impersonation Audit")
+
+	return nil
+}
+
+// GetImpersonationAudit returns one page of audit entries, newest first, with the total
+// number of entries matching the filter.
+func (c *ImpersonationClient) GetImpersonationAudit(ctx context.Context, filter *model.ImpersonationAuditFilter) ([]*model.ImpersonationAudit, int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetImpersonationAudit")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	var conditions []string
+	var args []interface{}
+
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+
+	if filter.Username != "" {
+		conditions = append(conditions, "username = ?")
+		args = append(args, filter.Username)
+	}
+
+	if filter.SessionID != "" {
+		conditions = append(conditions, "session_id = ?")
+		args = append(args, filter.SessionID)
+	}
+
+	if institutionID, ok := utils.GetTenant(ctx); ok {
+		conditions = append(conditions, "COALESCE(institution_id, '') = ?")
+		args = append(args, institutionID)
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.From)
+	}
+
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+
+	result := c.db.Debug().WithContext(ctx).Raw("SELECT COUNT(*) FROM impersonation_audit"+where, args...).Scan(&total)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, 0, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	var response []*model.ImpersonationAudit
+
+	query := "SELECT id, actor, username, COALESCE(institution_id, '') AS institution_id, session_id, method, path, status, blocked, reason, ip_address, created_at FROM impersonation_audit" + where + " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	result = c.db.Debug().WithContext(ctx).Raw(query, append(args, filter.Limit, filter.Offset)...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, 0, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", total)
+
+	return response, total, nil
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type InterfaceInstitutionClient interface {
+	CreateInstitution(ctx context.Context, institution *model.Institution) error
+	GetAllInstitution(ctx context.Context) ([]*model.Institution, error)
+	GetInstitutionByID(ctx context.Context, id string) (*model.Institution, error)
+	UpdateInstitution(ctx context.Context, institution *model.Institution) error
+	DeleteInstitution(ctx context.Context, id string) error
+	GetInstitutionUsername(ctx context.Context, id string) ([]string, error)
+}
+
+type InstitutionClient struct {
+	db *gorm.DB
+}
+
+func NewInstitutionClient(db *gorm.DB) *InstitutionClient {
+	return &InstitutionClient{db: db}
+}
+
+func (c *InstitutionClient) CreateInstitution(ctx context.Context, req *model.Institution) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.ID, req.Name, req.Code, req.Timezone, req.Status, req.ContactName, req.ContactEmail, req.ContactPhone, req.StorageBucket, req.CreatedAt, req.CreatedBy, req.UpdatedAt, req.UpdatedBy)
+
+	query := "INSERT INTO institution (id, name, code, timezone, status, contact_name, contact_email, contact_phone, storage_bucket, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		if mysqlErr, ok := result.Error.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			utils.LogEventError(span, errors.New("institution code already exists"))
+			return model.ThrowError(http.StatusBadRequest, errors.New("institution code already exists"))
+		}
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Institution")
+
+	return nil
+}
+
+func (c *InstitutionClient) GetAllInstitution(ctx context.Context) ([]*model.Institution, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetAllInstitution")
+	defer span.Finish()
+
+	var response []*model.Institution
+
+	tenant, tenantArgs := tenantCondition(ctx, "id")
+
+	query := "SELECT * FROM institution WHERE 1 = 1" + tenant + " ORDER BY name"
+	result := c.db.Debug().WithContext(ctx).Raw(query, tenantArgs...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *InstitutionClient) GetInstitutionByID(ctx context.Context, id string) (*model.Institution, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetInstitutionByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var response model.Institution
+
+	tenant, tenantArgs := tenantCondition(ctx, "id")
+
+	query := "SELECT * FROM institution WHERE id = ?" + tenant
+	result := c.db.Debug().WithContext(ctx).Raw(query, append([]interface{}{id}, tenantArgs...)...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("institution not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("institution not found"))
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return &response, nil
+}
+
+func (c *InstitutionClient) UpdateInstitution(ctx context.Context, req *model.Institution) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.Name, req.Code, req.Timezone, req.Status, req.ContactName, req.ContactEmail, req.ContactPhone, req.StorageBucket, req.UpdatedAt, req.UpdatedBy, req.ID)
+
+	tenant, tenantArgs := tenantCondition(ctx, "id")
+	args = append(args, tenantArgs...)
+
+	query := "UPDATE institution SET name = ?, code = ?, timezone = ?, status = ?, contact_name = ?, contact_email = ?, contact_phone = ?, storage_bucket = ?, updated_at = ?, updated_by = ? WHERE id = ?" + tenant
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		if mysqlErr, ok := result.Error.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			utils.LogEventError(span, errors.New("institution code already exists"))
+			return model.ThrowError(http.StatusBadRequest, errors.New("institution code already exists"))
+		}
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("institution not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("institution not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Institution")
+
+	return nil
+}
+
+// DeleteInstitution removes an institution that nothing refers to any more. Institutions
+// that still have users, face datasets or trained models have to be deactivated instead.
+func (c *InstitutionClient) DeleteInstitution(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	tenant, tenantArgs := tenantCondition(ctx, "id")
+
+	result := c.db.Debug().WithContext(ctx).Exec("DELETE FROM institution WHERE id = ?"+tenant, append([]interface{}{id}, tenantArgs...)...)
+
+	if result.Error != nil {
+		if mysqlErr, ok := result.Error.(*mysql.MySQLError); ok && mysqlErr.Number == 1451 {
+			utils.LogEventError(span, errors.New("institution is still in use"))
+			return model.ThrowError(http.StatusConflict, errors.New("institution still has users, datasets or models, deactivate it instead"))
+		}
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("institution not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("institution not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Institution")
+
+	return nil
+}
+
+func (c *InstitutionClient) GetInstitutionUsername(ctx context.Context, id string) ([]string, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetInstitutionUsername")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var response []string
+
+	query := "SELECT username FROM users WHERE institution_id = ?"
+	result := c.db.Debug().WithContext(ctx).Raw(query, id).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", len(response))
+
+	return response, nil
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type InterfaceInvitationClient interface {
+	CreateInvitation(ctx context.Context, invitation *model.Invitation) error
+	GetAllInvitation(ctx context.Context) ([]*model.Invitation, error)
+	GetInvitationByToken(ctx context.Context, tokenHash string) (*model.Invitation, error)
+	AcceptInvitation(ctx context.Context, invitation *model.Invitation, user *model.User) error
+	DeleteInvitation(ctx context.Context, id string) error
+}
+
+type InvitationClient struct {
+	db *gorm.DB
+}
+
+func NewInvitationClient(db *gorm.DB) *InvitationClient {
+	return &InvitationClient{db: db}
+}
+
+func (c *InvitationClient) CreateInvitation(ctx context.Context, req *model.Invitation) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateInvitation")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.ID, req.TokenHash, req.Email, req.RoleID, req.InstitutionID, req.ExpiredAt, req.CreatedAt, req.CreatedBy)
+
+	query := "INSERT INTO user_invitation (id, token_hash, email, role_id, institution_id, expired_at, created_at, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Invitation")
+
+	return nil
+}
+
+func (c *InvitationClient) GetAllInvitation(ctx context.Context) ([]*model.Invitation, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetAllInvitation")
+	defer span.Finish()
+
+	var response []*model.Invitation
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	query := "SELECT * FROM user_invitation WHERE 1 = 1" + tenant + " ORDER BY created_at DESC"
+	result := c.db.Debug().WithContext(ctx).Raw(query, tenantArgs...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *InvitationClient) GetInvitationByToken(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetInvitationByToken")
+	defer span.Finish()
+
+	var response model.Invitation
+
+	query := "SELECT * FROM user_invitation WHERE token_hash = ?"
+	result := c.db.Debug().WithContext(ctx).Raw(query, tokenHash).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("invitation not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("invitation not found"))
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return &response, nil
+}
+
+// AcceptInvitation marks an invitation as used and creates the invited user in one
+// transaction, so an invitation can never produce more than one account.
+func (c *InvitationClient) AcceptInvitation(ctx context.Context, invitation *model.Invitation, user *model.User) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: AcceptInvitation")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", invitation.ID)
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		query := "UPDATE user_invitation SET used_at = ?, used_by = ? WHERE id = ? AND used_at IS NULL AND expired_at > ?"
+		result := tx.Debug().Exec(query, now, user.Username, invitation.ID, now)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return model.ThrowError(http.StatusGone, errors.New("invitation has expired or was already used"))
+		}
+
+		var args []interface{}
+		args = append(args, user.Username, user.Email, user.Password, user.Fullname, user.Shortname, user.RoleID, user.InstitutionID, now)
+
+		query = "INSERT INTO users (username, email, password, fullname, shortname, role_id, institution_id, created_at) VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)"
+		return tx.Debug().Exec(query, args...).Error
+	})
+
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			switch mysqlErr.Number {
+			case 1062: // Duplicate entry
+				utils.LogEventError(span, errors.New("username or email already exists"))
+				return model.ThrowError(http.StatusBadRequest, errors.New("username or email already exists"))
+			}
+		}
+		utils.LogEventError(span, err)
+		if _, ok := err.(*model.ErrorResponse); ok {
+			return err
+		}
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Accept Invitation")
+
+	return nil
+}
+
+func (c *InvitationClient) DeleteInvitation(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteInvitation")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	query := "DELETE FROM user_invitation WHERE id = ? AND used_at IS NULL" + tenant
+	result := c.db.Debug().WithContext(ctx).Exec(query, append([]interface{}{id}, tenantArgs...)...)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("invitation not found or already used"))
+		return model.ThrowError(http.StatusNotFound, errors.New("invitation not found or already used"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Invitation")
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"face-recognition-svc/app/config"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+type LdapClient struct {
+	cfg *config.LDAP
+}
+
+func NewLdapClient(cfg *config.Config) *LdapClient {
+	return &LdapClient{cfg: &cfg.Auth.LDAP}
+}
+
+// Authenticate looks the user up with the service account and then binds as the user to
+// check the password.
+func (c *LdapClient) Authenticate(ctx context.Context, username string, password string) (*model.ExternalUser, error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: LdapAuthenticate")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	// An empty password would be an unauthenticated bind, which most servers accept.
+	if password == "" {
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid credentials"))
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusServiceUnavailable, err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusServiceUnavailable, fmt.Errorf("ldap service bind failed: %w", err))
+	}
+
+	emailAttribute := defaultString(c.cfg.EmailAttribute, "mail")
+	nameAttribute := defaultString(c.cfg.NameAttribute, "displayName")
+	groupAttribute := defaultString(c.cfg.GroupAttribute, "memberOf")
+
+	search := ldap.NewSearchRequest(
+		c.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false,
+		fmt.Sprintf(defaultString(c.cfg.UserFilter, "(sAMAccountName=%s)"), ldap.EscapeFilter(username)),
+		[]string{emailAttribute, nameAttribute, groupAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(search)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusServiceUnavailable, err)
+	}
+
+	if result == nil || len(result.Entries) == 0 {
+		utils.LogEventError(span, errors.New("ldap user not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+
+	if len(result.Entries) > 1 {
+		utils.LogEventError(span, errors.New("ldap user filter matched more than one entry"))
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid credentials"))
+	}
+
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		utils.LogEventError(span, err)
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid credentials"))
+		}
+		return nil, model.ThrowError(http.StatusServiceUnavailable, err)
+	}
+
+	user := &model.ExternalUser{
+		Username:      username,
+		Email:         entry.GetAttributeValue(emailAttribute),
+		Fullname:      entry.GetAttributeValue(nameAttribute),
+		RoleID:        c.matchGroupRole(entry.GetAttributeValues(groupAttribute)),
+		DefaultRoleID: c.cfg.DefaultRoleID,
+		InstitutionID: c.cfg.InstitutionID,
+		AutoProvision: c.cfg.AutoProvision,
+	}
+
+	utils.LogEvent(span, "Response", map[string]string{"dn": entry.DN, "role_id": user.RoleID})
+
+	return user, nil
+}
+
+// dial connects to the directory. The certificate is checked against the host of the URL,
+// StartTLS has no other way to learn it.
+func (c *LdapClient) dial() (*ldap.Conn, error) {
+	ldapURL, err := url.Parse(c.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         ldapURL.Hostname(),
+		InsecureSkipVerify: c.cfg.InsecureSkipVerify,
+	}
+
+	if c.cfg.CAFile != "" {
+		pem, err := os.ReadFile(c.cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.cfg.CAFile)
+		}
+	}
+
+	conn, err := ldap.DialURL(c.cfg.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetTimeout(10 * time.Second)
+
+	if c.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// matchGroupRole returns the role of the first configured group the user is a member of.
+func (c *LdapClient) matchGroupRole(groups []string) string {
+	for _, rule := range c.cfg.GroupRoles {
+		for _, group := range groups {
+			if strings.EqualFold(group, rule.Group) {
+				return rule.RoleID
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceLoginHistoryClient interface {
+	CreateLoginHistory(ctx context.Context, history *model.LoginHistory) error
+	GetLoginHistory(ctx context.Context, filter *model.LoginHistoryFilter) ([]*model.LoginHistory, int64, error)
+}
+
+type LoginHistoryClient struct {
+	db *gorm.DB
+}
+
+func NewLoginHistoryClient(db *gorm.DB) *LoginHistoryClient {
+	return &LoginHistoryClient{db: db}
+}
+
+// CreateLoginHistory records a login attempt. When the institution is not known yet it is
+// taken from the user, if the username exists.
+func (c *LoginHistoryClient) CreateLoginHistory(ctx context.Context, req *model.LoginHistory) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateLoginHistory")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.Username, req.InstitutionID, req.Username, req.SessionID, req.Method, req.Outcome, req.Reason, req.IPAddress, req.UserAgent, req.CreatedAt)
+
+	query := "INSERT INTO login_history (username, institution_id, session_id, method, outcome, reason, ip_address, user_agent, created_at) VALUES (?, COALESCE(NULLIF(?, ''), (SELECT institution_id FROM users WHERE username = ?)), NULLIF(?, ''), ?, ?, ?, ?, ?, ?)"
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Login History")
+
+	return nil
+}
+
+// GetLoginHistory returns one page of login attempts, newest first, with the total number
+// of attempts matching the filter.
+func (c *LoginHistoryClient) GetLoginHistory(ctx context.Context, filter *model.LoginHistoryFilter) ([]*model.LoginHistory, int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetLoginHistory")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	var conditions []string
+	var args []interface{}
+
+	if filter.Username != "" {
+		conditions = append(conditions, "username = ?")
+		args = append(args, filter.Username)
+	}
+
+	if filter.InstitutionID != "" {
+		conditions = append(conditions, "institution_id = ?")
+		args = append(args, filter.InstitutionID)
+	}
+
+	if institutionID, ok := utils.GetTenant(ctx); ok {
+		conditions = append(conditions, "COALESCE(institution_id, '') = ?")
+		args = append(args, institutionID)
+	}
+
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.From)
+	}
+
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+
+	result := c.db.Debug().WithContext(ctx).Raw("SELECT COUNT(*) FROM login_history"+where, args...).Scan(&total)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, 0, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	var response []*model.LoginHistory
+
+	query := "SELECT id, username, COALESCE(institution_id, '') AS institution_id, COALESCE(session_id, '') AS session_id, method, outcome, reason, ip_address, user_agent, created_at FROM login_history" + where + " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	result = c.db.Debug().WithContext(ctx).Raw(query, append(args, filter.Limit, filter.Offset)...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, 0, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", total)
+
+	return response, total, nil
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceMfaClient interface {
+	GetUserMfa(ctx context.Context, username string) (*model.UserMfa, error)
+	SaveUserMfa(ctx context.Context, mfa *model.UserMfa) error
+	EnableUserMfa(ctx context.Context, username string, recoveryCodeHashes []string) error
+	ReplaceRecoveryCode(ctx context.Context, username string, recoveryCodeHashes []string) error
+	UseRecoveryCode(ctx context.Context, username string, recoveryCodeHash string) (bool, error)
+	DeleteUserMfa(ctx context.Context, username string) error
+}
+
+type MfaClient struct {
+	db *gorm.DB
+}
+
+func NewMfaClient(db *gorm.DB) *MfaClient {
+	return &MfaClient{db: db}
+}
+
+// GetUserMfa returns the TOTP enrollment of a user, or nil when there is none.
+func (c *MfaClient) GetUserMfa(ctx context.Context, username string) (*model.UserMfa, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetUserMfa")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	var response model.UserMfa
+
+	query := "SELECT * FROM user_mfa WHERE username = ?"
+	result := c.db.Debug().WithContext(ctx).Raw(query, username).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return &response, nil
+}
+
+// SaveUserMfa stores a pending, not yet enabled, enrollment replacing any previous one.
+func (c *MfaClient) SaveUserMfa(ctx context.Context, req *model.UserMfa) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: SaveUserMfa")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	query := "INSERT INTO user_mfa (username, secret, is_enabled, created_at) VALUES (?, ?, 0, ?) ON DUPLICATE KEY UPDATE secret = VALUES(secret), is_enabled = 0, created_at = VALUES(created_at), enabled_at = NULL"
+	result := c.db.Debug().WithContext(ctx).Exec(query, req.Username, req.Secret, req.CreatedAt)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Save User Mfa")
+
+	return nil
+}
+
+func (c *MfaClient) EnableUserMfa(ctx context.Context, username string, recoveryCodeHashes []string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: EnableUserMfa")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Debug().Exec("UPDATE user_mfa SET is_enabled = 1, enabled_at = ? WHERE username = ?", time.Now(), username)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return model.ThrowError(http.StatusNotFound, errors.New("two-factor enrollment not found"))
+		}
+
+		return replaceRecoveryCode(tx, username, recoveryCodeHashes)
+	})
+
+	if err != nil {
+		utils.LogEventError(span, err)
+		if _, ok := err.(*model.ErrorResponse); ok {
+			return err
+		}
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Enable User Mfa")
+
+	return nil
+}
+
+func (c *MfaClient) ReplaceRecoveryCode(ctx context.Context, username string, recoveryCodeHashes []string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ReplaceRecoveryCode")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCode(tx, username, recoveryCodeHashes)
+	})
+
+	if err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Replace Recovery Code")
+
+	return nil
+}
+
+func replaceRecoveryCode(tx *gorm.DB, username string, recoveryCodeHashes []string) error {
+	if err := tx.Debug().Exec("DELETE FROM user_mfa_recovery_code WHERE username = ?", username).Error; err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		query := "INSERT INTO user_mfa_recovery_code (username, code_hash, created_at) VALUES (?, ?, ?)"
+		if err := tx.Debug().Exec(query, username, hash, time.Now()).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode consumes a recovery code, reporting whether it existed.
+func (c *MfaClient) UseRecoveryCode(ctx context.Context, username string, recoveryCodeHash string) (bool, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UseRecoveryCode")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	query := "DELETE FROM user_mfa_recovery_code WHERE username = ? AND code_hash = ?"
+	result := c.db.Debug().WithContext(ctx).Exec(query, username, recoveryCodeHash)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return false, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", result.RowsAffected)
+
+	return result.RowsAffected > 0, nil
+}
+
+func (c *MfaClient) DeleteUserMfa(ctx context.Context, username string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteUserMfa")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Debug().Exec("DELETE FROM user_mfa_recovery_code WHERE username = ?", username).Error; err != nil {
+			return err
+		}
+
+		return tx.Debug().Exec("DELETE FROM user_mfa WHERE username = ?", username).Error
+	})
+
+	if err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete User Mfa")
+
+	return nil
+}
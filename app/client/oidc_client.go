@@ -0,0 +1,392 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/app/config"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const oidcMetadataExpiry = time.Hour
+
+type InterfaceOIDCClient interface {
+	GetProvider(ctx context.Context, institutionID string) (*config.OIDCProvider, error)
+	GetAuthorizationURL(ctx context.Context, provider *config.OIDCProvider, state string, nonce string, codeChallenge string) (string, error)
+	ExchangeCode(ctx context.Context, provider *config.OIDCProvider, code string, codeVerifier string, nonce string) (*model.OIDCIdentity, error)
+}
+
+type OIDCClient struct {
+	cfg        *config.Config
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	metadata map[string]*oidcMetadata
+}
+
+// oidcMetadata caches the discovery document and signing keys of an issuer.
+type oidcMetadata struct {
+	discovery *model.OIDCDiscovery
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+func NewOIDCClient(cfg *config.Config) *OIDCClient {
+	return &OIDCClient{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		metadata:   map[string]*oidcMetadata{},
+	}
+}
+
+func (c *OIDCClient) GetProvider(ctx context.Context, institutionID string) (*config.OIDCProvider, error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: GetProvider")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	for i := range c.cfg.OIDC.Providers {
+		if c.cfg.OIDC.Providers[i].InstitutionID == institutionID {
+			return &c.cfg.OIDC.Providers[i], nil
+		}
+	}
+
+	utils.LogEventError(span, errors.New("identity provider not configured"))
+	return nil, model.ThrowError(http.StatusNotFound, errors.New("identity provider not configured for institution"))
+}
+
+func (c *OIDCClient) GetAuthorizationURL(ctx context.Context, provider *config.OIDCProvider, state string, nonce string, codeChallenge string) (string, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetAuthorizationURL")
+	defer span.Finish()
+
+	meta, err := c.getMetadata(ctx, provider.Issuer, false)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return "", err
+	}
+
+	scopes := provider.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.ClientID)
+	query.Set("redirect_uri", provider.RedirectURI)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(meta.discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return meta.discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// ExchangeCode redeems an authorization code at the token endpoint and returns the identity
+// from the verified ID token.
+func (c *OIDCClient) ExchangeCode(ctx context.Context, provider *config.OIDCProvider, code string, codeVerifier string, nonce string) (*model.OIDCIdentity, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ExchangeCode")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", provider.Issuer)
+
+	meta, err := c.getMetadata(ctx, provider.Issuer, false)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.RedirectURI)
+	form.Set("client_id", provider.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if provider.ClientSecret != "" {
+		form.Set("client_secret", provider.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token model.OIDCTokenResponse
+	if err := c.doJSON(req, &token); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusUnauthorized, fmt.Errorf("authorization code exchange failed: %w", err))
+	}
+
+	if token.IDToken == "" {
+		utils.LogEventError(span, errors.New("token response has no id_token"))
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("identity provider did not return an id_token"))
+	}
+
+	identity, err := c.verifyIDToken(ctx, provider, meta, token.IDToken, nonce)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusUnauthorized, fmt.Errorf("invalid id_token: %w", err))
+	}
+
+	utils.LogEvent(span, "Response", identity.Subject)
+
+	return identity, nil
+}
+
+func (c *OIDCClient) verifyIDToken(ctx context.Context, provider *config.OIDCProvider, meta *oidcMetadata, rawToken string, nonce string) (*model.OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := c.findKey(ctx, provider.Issuer, meta, kid)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(meta.discovery.Issuer),
+		jwt.WithAudience(provider.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claimString(claims, "nonce") != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+
+	identity := &model.OIDCIdentity{
+		Subject:       claimString(claims, "sub"),
+		Username:      claimString(claims, defaultString(provider.UsernameClaim, "preferred_username")),
+		Email:         claimString(claims, defaultString(provider.EmailClaim, "email")),
+		EmailVerified: claims["email_verified"] != false,
+		Name:          claimString(claims, "name"),
+		Groups:        claimStrings(claims, defaultString(provider.GroupsClaim, "groups")),
+		Amr:           claimStrings(claims, "amr"),
+	}
+
+	if identity.Subject == "" {
+		return nil, errors.New("missing sub")
+	}
+
+	return identity, nil
+}
+
+// findKey returns the signing key for kid, refetching the key set once when the kid is
+// unknown so that key rotation at the identity provider is picked up.
+func (c *OIDCClient) findKey(ctx context.Context, issuer string, meta *oidcMetadata, kid string) (interface{}, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if kid == "" && len(meta.keys) == 1 {
+			for _, key := range meta.keys {
+				return key, nil
+			}
+		}
+
+		if key, ok := meta.keys[kid]; ok {
+			return key, nil
+		}
+
+		if attempt == 0 {
+			var err error
+			meta, err = c.getMetadata(ctx, issuer, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (c *OIDCClient) getMetadata(ctx context.Context, issuer string, refresh bool) (*oidcMetadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if meta, ok := c.metadata[issuer]; ok && !refresh && time.Since(meta.fetchedAt) < oidcMetadataExpiry {
+		return meta, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	var discovery model.OIDCDiscovery
+	if err := c.doJSON(req, &discovery); err != nil {
+		return nil, model.ThrowError(http.StatusBadGateway, fmt.Errorf("openid discovery failed: %w", err))
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, model.ThrowError(http.StatusBadGateway, fmt.Errorf("discovery issuer %s does not match %s", discovery.Issuer, issuer))
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, discovery.JwksURI, nil)
+	if err != nil {
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.doJSON(req, &jwks); err != nil {
+		return nil, model.ThrowError(http.StatusBadGateway, fmt.Errorf("fetching jwks failed: %w", err))
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	meta := &oidcMetadata{
+		discovery: &discovery,
+		keys:      keys,
+		fetchedAt: time.Now(),
+	}
+	c.metadata[issuer] = meta
+
+	return meta, nil
+}
+
+func (c *OIDCClient) doJSON(req *http.Request, out interface{}) error {
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.New("request failed with status " + res.Status + ": " + string(body))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// jsonWebKey holds the members of an identity provider's JWK needed to verify signatures.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimStrings reads a claim that may be a list of strings or a single string.
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		res := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+func defaultString(value string, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,345 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"face-recognition-svc/app/config"
+	"face-recognition-svc/app/model"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is an OpenID provider serving discovery, a JWKS and a token endpoint that
+// checks the PKCE verifier against the challenge of the authorization request.
+type mockIdP struct {
+	server *httptest.Server
+
+	mu         sync.Mutex
+	key        *rsa.PrivateKey
+	kid        string
+	jwksHits   int
+	codes      map[string]url.Values
+	extraClaim jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	idp := &mockIdP{
+		key:   generateRSAKey(t),
+		kid:   "key-1",
+		codes: map[string]url.Values{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+	// Claims an issuer it does not serve, discovery has to be refused.
+	mux.HandleFunc("/tenant/.well-known/openid-configuration", idp.discovery)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func (idp *mockIdP) provider() *config.OIDCProvider {
+	return &config.OIDCProvider{
+		InstitutionID: "inst-1",
+		Issuer:        idp.server.URL,
+		ClientID:      "face-app",
+		ClientSecret:  "secret",
+		RedirectURI:   "https://face.example.com/callback",
+	}
+}
+
+// authorize plays the user logging in at the provider: it accepts the authorization
+// request and returns the code that is sent back to the redirect URI.
+func (idp *mockIdP) authorize(t *testing.T, authorizationURL string) string {
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	code := "code-" + u.Query().Get("state")
+	idp.codes[code] = u.Query()
+
+	return code
+}
+
+// rotate replaces the signing key, tokens are signed with the new kid from now on.
+func (idp *mockIdP) rotate(t *testing.T, kid string) {
+	key := generateRSAKey(t)
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	idp.key = key
+	idp.kid = kid
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &model.OIDCDiscovery{
+		Issuer:                idp.server.URL,
+		AuthorizationEndpoint: idp.server.URL + "/authorize",
+		TokenEndpoint:         idp.server.URL + "/token",
+		JwksURI:               idp.server.URL + "/jwks",
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	idp.jwksHits++
+
+	encode := base64.RawURLEncoding.EncodeToString
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": idp.kid,
+			"use": "sig",
+			"n":   encode(idp.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	request, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+
+	if !ok ||
+		r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != request.Get("client_id") ||
+		r.PostForm.Get("client_secret") != "secret" ||
+		r.PostForm.Get("redirect_uri") != request.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if request.Get("code_challenge_method") != "S256" || base64.RawURLEncoding.EncodeToString(challenge[:]) != request.Get("code_challenge") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"aud":                request.Get("client_id"),
+		"sub":                "subject-1",
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"nonce":              request.Get("nonce"),
+		"preferred_username": "jane",
+		"email":              "jane@example.com",
+		"email_verified":     true,
+		"name":               "Jane Doe",
+		"groups":             []string{"staff", "admins"},
+		"amr":                []string{"pwd", "mfa"},
+	}
+	for name, value := range idp.extraClaim {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &model.OIDCTokenResponse{
+		AccessToken: "access-token",
+		IDToken:     idToken,
+		TokenType:   "Bearer",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// login runs the authorization code flow against the provider the way the controller does.
+func login(t *testing.T, c *OIDCClient, idp *mockIdP, verifier string, nonce string) (*model.OIDCIdentity, error) {
+	ctx := context.Background()
+	provider := idp.provider()
+
+	challenge := sha256.Sum256([]byte("verifier"))
+
+	authorizationURL, err := c.GetAuthorizationURL(ctx, provider, "state", "nonce", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c.ExchangeCode(ctx, provider, idp.authorize(t, authorizationURL), verifier, nonce)
+}
+
+func errorCode(err error) int {
+	if data, ok := err.(*model.ErrorResponse); ok {
+		return data.Code
+	}
+	return 0
+}
+
+func TestOIDCClientGetAuthorizationURL(t *testing.T) {
+	idp := newMockIdP(t)
+	c := NewOIDCClient(&config.Config{})
+
+	authorizationURL, err := c.GetAuthorizationURL(context.Background(), idp.provider(), "state", "nonce", "challenge")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := u.Scheme + "://" + u.Host + u.Path; got != idp.server.URL+"/authorize" {
+		t.Errorf("authorization endpoint = %s, want %s/authorize", got, idp.server.URL)
+	}
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "face-app",
+		"redirect_uri":          "https://face.example.com/callback",
+		"scope":                 "openid email profile",
+		"state":                 "state",
+		"nonce":                 "nonce",
+		"code_challenge":        "challenge",
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := u.Query().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestOIDCClientExchangeCode(t *testing.T) {
+	idp := newMockIdP(t)
+	c := NewOIDCClient(&config.Config{})
+
+	identity, err := login(t, c, idp, "verifier", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if identity.Subject != "subject-1" || identity.Username != "jane" || identity.Email != "jane@example.com" || !identity.EmailVerified || identity.Name != "Jane Doe" {
+		t.Errorf("identity = %+v", identity)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[0] != "staff" || identity.Groups[1] != "admins" {
+		t.Errorf("groups = %v, want [staff admins]", identity.Groups)
+	}
+	if len(identity.Amr) != 2 || identity.Amr[1] != "mfa" {
+		t.Errorf("amr = %v, want [pwd mfa]", identity.Amr)
+	}
+}
+
+func TestOIDCClientExchangeCodeRejectsWrongVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	c := NewOIDCClient(&config.Config{})
+
+	_, err := login(t, c, idp, "another-verifier", "nonce")
+	if errorCode(err) != http.StatusUnauthorized {
+		t.Fatalf("err = %v, want 401", err)
+	}
+}
+
+func TestOIDCClientExchangeCodeRejectsInvalidIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		nonce  string
+	}{
+		{name: "nonce mismatch", nonce: "another-nonce"},
+		{name: "other audience", claims: jwt.MapClaims{"aud": "another-app"}},
+		{name: "other issuer", claims: jwt.MapClaims{"iss": "https://idp.example.com"}},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "missing subject", claims: jwt.MapClaims{"sub": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.extraClaim = tt.claims
+			c := NewOIDCClient(&config.Config{})
+
+			nonce := tt.nonce
+			if nonce == "" {
+				nonce = "nonce"
+			}
+
+			_, err := login(t, c, idp, "verifier", nonce)
+			if errorCode(err) != http.StatusUnauthorized {
+				t.Fatalf("err = %v, want 401", err)
+			}
+		})
+	}
+}
+
+func TestOIDCClientRefetchesRotatedKeys(t *testing.T) {
+	idp := newMockIdP(t)
+	c := NewOIDCClient(&config.Config{})
+
+	if _, err := login(t, c, idp, "verifier", "nonce"); err != nil {
+		t.Fatal(err)
+	}
+
+	idp.rotate(t, "key-2")
+
+	if _, err := login(t, c, idp, "verifier", "nonce"); err != nil {
+		t.Fatal(err)
+	}
+
+	if idp.jwksHits != 2 {
+		t.Errorf("jwks fetched %d times, want 2", idp.jwksHits)
+	}
+}
+
+func TestOIDCClientRejectsDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	c := NewOIDCClient(&config.Config{})
+
+	provider := idp.provider()
+	provider.Issuer = idp.server.URL + "/tenant"
+
+	_, err := c.GetAuthorizationURL(context.Background(), provider, "state", "nonce", "challenge")
+	if errorCode(err) != http.StatusBadGateway {
+		t.Fatalf("err = %v, want 502", err)
+	}
+}
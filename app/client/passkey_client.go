@@ -0,0 +1,400 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"face-recognition-svc/app/config"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InterfacePasskeyClient interface {
+	GetPasskeyByUser(ctx context.Context, username string) ([]*model.Passkey, error)
+	GetPasskeyByCredentialID(ctx context.Context, credentialID string) (*model.Passkey, error)
+	CreatePasskey(ctx context.Context, passkey *model.Passkey) error
+	UpdatePasskeyUsage(ctx context.Context, passkey *model.Passkey) error
+	DeletePasskey(ctx context.Context, username string, id string) error
+
+	BeginRegistration(ctx context.Context, user *model.UserDetail, passkeys []*model.Passkey) (*protocol.CredentialCreation, *webauthn.SessionData, error)
+	FinishRegistration(ctx context.Context, user *model.UserDetail, passkeys []*model.Passkey, session *webauthn.SessionData, response []byte) (*model.Passkey, error)
+	BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, *webauthn.SessionData, error)
+	FinishLogin(ctx context.Context, session *webauthn.SessionData, response []byte) (*model.Passkey, error)
+}
+
+type PasskeyClient struct {
+	db       *gorm.DB
+	webAuthn *webauthn.WebAuthn
+}
+
+var errPasskeyDisabled = model.ThrowError(http.StatusNotFound, errors.New("passkey login is not configured"))
+
+// NewPasskeyClient returns a client whose ceremonies fail with 404 while auth.webauthn.rpId
+// is not configured, stored passkeys can still be listed and removed.
+func NewPasskeyClient(db *gorm.DB, cfg *config.Config) (*PasskeyClient, error) {
+	c := &PasskeyClient{db: db}
+
+	if cfg.Auth.WebAuthn.RPID == "" {
+		return c, nil
+	}
+
+	displayName := defaultString(cfg.Auth.WebAuthn.RPDisplayName, "Face Recognition")
+
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.Auth.WebAuthn.RPID,
+		RPDisplayName: displayName,
+		RPOrigins:     cfg.Auth.WebAuthn.RPOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.webAuthn = webAuthn
+
+	return c, nil
+}
+
+// passkeyUser adapts a user and their stored passkeys to webauthn.User. The user handle is a
+// hash of the username so it fits the 64 byte limit and does not reveal the username.
+type passkeyUser struct {
+	user     *model.UserDetail
+	passkeys []*model.Passkey
+}
+
+func passkeyUserHandle(username string) []byte {
+	sum := sha256.Sum256([]byte(username))
+	return sum[:]
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return passkeyUserHandle(u.user.Username)
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return defaultString(u.user.Fullname, u.user.Username)
+}
+
+func (u *passkeyUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+
+	for _, passkey := range u.passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(passkey.CredentialID)
+		if err != nil {
+			continue
+		}
+
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range strings.Split(passkey.Transports, ",") {
+			if transport != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
+
+func (c *PasskeyClient) GetPasskeyByUser(ctx context.Context, username string) ([]*model.Passkey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetPasskeyByUser")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	var response []*model.Passkey
+
+	query := "SELECT * FROM user_passkey WHERE username = ? ORDER BY created_at"
+	result := c.db.Debug().WithContext(ctx).Raw(query, username).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", len(response))
+
+	return response, nil
+}
+
+// GetPasskeyByCredentialID returns the passkey with the given credential id, or nil when
+// there is none.
+func (c *PasskeyClient) GetPasskeyByCredentialID(ctx context.Context, credentialID string) (*model.Passkey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetPasskeyByCredentialID")
+	defer span.Finish()
+
+	var response model.Passkey
+
+	query := "SELECT * FROM user_passkey WHERE credential_id = ?"
+	result := c.db.Debug().WithContext(ctx).Raw(query, credentialID).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	utils.LogEvent(span, "Response", response.ID)
+
+	return &response, nil
+}
+
+func (c *PasskeyClient) CreatePasskey(ctx context.Context, req *model.Passkey) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreatePasskey")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.ID, req.CredentialID, req.Username, req.Name, req.PublicKey, req.AttestationType, req.Transports, req.AAGUID, req.SignCount, req.BackupEligible, req.BackupState, req.CreatedAt)
+
+	query := "INSERT INTO user_passkey (id, credential_id, username, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Passkey")
+
+	return nil
+}
+
+func (c *PasskeyClient) UpdatePasskeyUsage(ctx context.Context, req *model.Passkey) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdatePasskeyUsage")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req.ID)
+
+	query := "UPDATE user_passkey SET sign_count = ?, backup_state = ?, last_used_at = ? WHERE id = ?"
+	result := c.db.Debug().WithContext(ctx).Exec(query, req.SignCount, req.BackupState, req.LastUsedAt, req.ID)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Passkey Usage")
+
+	return nil
+}
+
+func (c *PasskeyClient) DeletePasskey(ctx context.Context, username string, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeletePasskey")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	query := "DELETE FROM user_passkey WHERE id = ? AND username = ?"
+	result := c.db.Debug().WithContext(ctx).Exec(query, id, username)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("passkey not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("passkey not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Passkey")
+
+	return nil
+}
+
+// BeginRegistration returns the options for navigator.credentials.create(). A discoverable
+// credential with user verification is required so it can be used without a username.
+func (c *PasskeyClient) BeginRegistration(ctx context.Context, user *model.UserDetail, passkeys []*model.Passkey) (*protocol.CredentialCreation, *webauthn.SessionData, error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: BeginPasskeyRegistration")
+	defer span.Finish()
+
+	if c.webAuthn == nil {
+		return nil, nil, errPasskeyDisabled
+	}
+
+	u := &passkeyUser{user: user, passkeys: passkeys}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(passkeys))
+	for _, credential := range u.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := c.webAuthn.BeginRegistration(u,
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		}),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return creation, session, nil
+}
+
+// FinishRegistration verifies the attestation response and returns the passkey to store.
+func (c *PasskeyClient) FinishRegistration(ctx context.Context, user *model.UserDetail, passkeys []*model.Passkey, session *webauthn.SessionData, response []byte) (*model.Passkey, error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: FinishPasskeyRegistration")
+	defer span.Finish()
+
+	if c.webAuthn == nil {
+		return nil, errPasskeyDisabled
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(response))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("invalid passkey registration response"))
+	}
+
+	credential, err := c.webAuthn.CreateCredential(&passkeyUser{user: user, passkeys: passkeys}, *session, parsed)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("passkey registration could not be verified"))
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	aaguid := ""
+	if id, err := uuid.FromBytes(credential.Authenticator.AAGUID); err == nil {
+		aaguid = id.String()
+	}
+
+	passkey := &model.Passkey{
+		ID:              uuid.New().String(),
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		Username:        user.Username,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          aaguid,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+
+	return passkey, nil
+}
+
+// BeginLogin returns the options for navigator.credentials.get() without an allow list, the
+// browser offers every passkey it holds for the relying party.
+func (c *PasskeyClient) BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: BeginPasskeyLogin")
+	defer span.Finish()
+
+	if c.webAuthn == nil {
+		return nil, nil, errPasskeyDisabled
+	}
+
+	assertion, session, err := c.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return assertion, session, nil
+}
+
+// FinishLogin verifies the assertion response against the stored passkey and returns it with
+// the new signature counter. A counter that did not increase means the credential may have
+// been cloned and the login is refused.
+func (c *PasskeyClient) FinishLogin(ctx context.Context, session *webauthn.SessionData, response []byte) (*model.Passkey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: FinishPasskeyLogin")
+	defer span.Finish()
+
+	if c.webAuthn == nil {
+		return nil, errPasskeyDisabled
+	}
+
+	errInvalidPasskey := model.ThrowError(http.StatusUnauthorized, errors.New("passkey could not be verified"))
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("invalid passkey login response"))
+	}
+
+	var passkey *model.Passkey
+	var lookupErr error
+
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		passkey, lookupErr = c.GetPasskeyByCredentialID(ctx, base64.RawURLEncoding.EncodeToString(rawID))
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+
+		if passkey == nil || !bytes.Equal(passkeyUserHandle(passkey.Username), userHandle) {
+			return nil, errors.New("passkey is not registered")
+		}
+
+		return &passkeyUser{
+			user:     &model.UserDetail{Username: passkey.Username},
+			passkeys: []*model.Passkey{passkey},
+		}, nil
+	}
+
+	credential, err := c.webAuthn.ValidateDiscoverableLogin(handler, *session, parsed)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		return nil, errInvalidPasskey
+	}
+
+	if credential.Authenticator.CloneWarning {
+		utils.LogEventError(span, errors.New("passkey signature counter did not increase"))
+		return nil, errInvalidPasskey
+	}
+
+	now := time.Now()
+	passkey.SignCount = credential.Authenticator.SignCount
+	passkey.BackupState = credential.Flags.BackupState
+	passkey.LastUsedAt = &now
+
+	utils.LogEvent(span, "Response", passkey.ID)
+
+	return passkey, nil
+}
@@ -2,9 +2,13 @@ package client
 
 import (
 	"context"
+	"errors"
 	"face-recognition-svc/app/model"
 	"face-recognition-svc/app/utils"
+	"net/http"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 )
 
@@ -13,15 +17,24 @@ type InterfaceRoleClient interface {
 	CreateNewRoleMapping(ctx context.Context, role *model.MenuRoleMapping) error
 	GetAllRoleMapping(ctx context.Context) ([]*model.MenuRoleMapping, error)
 	UpdateRoleMapping(ctx context.Context, req *model.MenuRoleMapping) error
+	GetRoleMappingByID(ctx context.Context, id string) (*model.MenuRoleMapping, error)
+	DeleteRoleMapping(ctx context.Context, id string) error
+	ApplyRoleMapping(ctx context.Context, created []*model.MenuRoleMapping, updated []*model.MenuRoleMapping, deleted []string) error
 
 	GetAllMenu(ctx context.Context) ([]*model.Menu, error)
 	CreateNewMenu(ctx context.Context, request *model.Menu) error
 	UpdateMenu(ctx context.Context, request *model.Menu) error
 	DeleteMenu(ctx context.Context, menuID string) error
+	CountChildMenu(ctx context.Context, menuID string) (int64, error)
 
 	GetAllRole(ctx context.Context) ([]*model.Role, error)
 	CreateNewRole(ctx context.Context, request *model.Role) error
 	UpdateRole(ctx context.Context, request *model.Role) error
+	UpdateRoleStatus(ctx context.Context, request *model.Role) error
+	GetRoleByID(ctx context.Context, id string) (*model.Role, error)
+	CountUserByRole(ctx context.Context, roleID string) (int64, error)
+	CountChildRole(ctx context.Context, roleID string) (int64, error)
+	DeleteRole(ctx context.Context, id string) error
 }
 
 type RoleClient struct {
@@ -100,7 +113,7 @@ func (r *RoleClient) GetAllMenu(ctx context.Context) ([]*model.Menu, error) {
 
 	var response []*model.Menu
 
-	query := "SELECT * FROM menu ORDER BY id ASC"
+	query := "SELECT * FROM menu ORDER BY sort_order ASC, menu_name ASC, id ASC"
 
 	err := r.db.Debug().Raw(query).Scan(&response).Error
 	if err != nil {
@@ -121,8 +134,8 @@ func (r *RoleClient) CreateNewMenu(ctx context.Context, req *model.Menu) error {
 
 	var args []interface{}
 
-	args = append(args, req.Id, req.MenuName, req.MenuRoute, req.CreatedAt, req.UpdatedAt, req.CreatedBy, req.UpdatedBy)
-	query := "INSERT INTO menu (id, menu_name, menu_route, created_at, updated_at, created_by, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	args = append(args, req.Id, req.MenuName, req.MenuRoute, req.ParentID, req.SortOrder, req.Icon, req.IsHidden, req.CreatedAt, req.UpdatedAt, req.CreatedBy, req.UpdatedBy)
+	query := "INSERT INTO menu (id, menu_name, menu_route, parent_id, sort_order, icon, is_hidden, created_at, updated_at, created_by, updated_by) VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?)"
 
 	err := r.db.Exec(query, args...).Error
 	if err != nil {
@@ -162,8 +175,8 @@ func (r *RoleClient) CreateNewRole(ctx context.Context, req *model.Role) error {
 
 	var args []interface{}
 
-	args = append(args, req.Id, req.RoleName, req.CreatedAt, req.UpdatedAt, req.CreatedBy, req.UpdatedBy)
-	query := "INSERT INTO role (id, role_name, created_at, updated_at, created_by, updated_by) VALUES (?, ?, ?, ?, ?, ?)"
+	args = append(args, req.Id, req.RoleName, req.RoleDesc, req.ParentID, req.IsActive, req.MfaRequired, req.IsSuperAdmin, req.CreatedAt, req.UpdatedAt, req.CreatedBy, req.UpdatedBy)
+	query := "INSERT INTO role (id, role_name, role_desc, parent_id, is_active, mfa_required, is_super_admin, created_at, updated_at, created_by, updated_by) VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?)"
 
 	err := r.db.Exec(query, args...).Error
 	if err != nil {
@@ -184,16 +197,213 @@ func (r *RoleClient) UpdateRole(ctx context.Context, req *model.Role) error {
 
 	var args []interface{}
 
-	args = append(args, req.RoleName, req.UpdatedAt, req.UpdatedBy, req.Id)
-	query := "UPDATE role SET role_name = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+	args = append(args, req.RoleName, req.RoleDesc, req.ParentID, req.MfaRequired, req.IsSuperAdmin, req.UpdatedAt, req.UpdatedBy, req.Id)
+	query := "UPDATE role SET role_name = ?, role_desc = ?, parent_id = NULLIF(?, ''), mfa_required = ?, is_super_admin = ?, updated_at = ?, updated_by = ? WHERE id = ?"
 
-	err := r.db.Exec(query, args...).Error
+	result := r.db.Exec(query, args...)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("role not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("role not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Role")
+
+	return nil
+}
+
+func (r *RoleClient) UpdateRoleStatus(ctx context.Context, req *model.Role) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateRoleStatus")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+
+	args = append(args, req.IsActive, req.UpdatedAt, req.UpdatedBy, req.Id)
+	query := "UPDATE role SET is_active = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+
+	result := r.db.Exec(query, args...)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("role not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("role not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Role Status")
+
+	return nil
+}
+
+func (r *RoleClient) GetRoleByID(ctx context.Context, id string) (*model.Role, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRoleByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var response model.Role
+
+	query := "SELECT * FROM role WHERE id = ?"
+
+	result := r.db.Debug().Raw(query, id).Scan(&response)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("role not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("role not found"))
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return &response, nil
+}
+
+// CountUserByRole counts the users having the role as primary role or through a grant that
+// has not expired yet.
+func (r *RoleClient) CountUserByRole(ctx context.Context, roleID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CountUserByRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", roleID)
+
+	var count int64
+
+	query := "SELECT COUNT(DISTINCT username) FROM (SELECT username FROM users WHERE role_id = ? UNION ALL SELECT username FROM user_role WHERE role_id = ? AND (expires_at IS NULL OR expires_at > ?)) AS holder"
+
+	err := r.db.Debug().Raw(query, roleID, roleID, time.Now()).Scan(&count).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	utils.LogEvent(span, "Response", count)
+
+	return count, nil
+}
+
+func (r *RoleClient) CountChildRole(ctx context.Context, roleID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CountChildRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", roleID)
+
+	var count int64
+
+	query := "SELECT COUNT(*) FROM role WHERE parent_id = ?"
+
+	err := r.db.Debug().Raw(query, roleID).Scan(&count).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	utils.LogEvent(span, "Response", count)
+
+	return count, nil
+}
+
+// DeleteRole removes a role together with its menu mappings and expired grants.
+func (r *RoleClient) DeleteRole(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var rowsAffected int64
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM menu_mapping WHERE role_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM user_role WHERE role_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		result := tx.Exec("DELETE FROM role WHERE id = ?", id)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
 	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1451 { // Row is still referenced
+			utils.LogEventError(span, errors.New("role is still in use"))
+			return model.ThrowError(http.StatusConflict, errors.New("role is still in use"))
+		}
 		utils.LogEventError(span, err)
 		return err
 	}
 
-	utils.LogEvent(span, "Response", "Success Update Role")
+	if rowsAffected == 0 {
+		utils.LogEventError(span, errors.New("role not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("role not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Role")
+
+	return nil
+}
+
+// ApplyRoleMapping inserts, updates and deletes menu mappings in one transaction. Nothing is
+// applied when a row to update or delete is already gone.
+func (r *RoleClient) ApplyRoleMapping(ctx context.Context, created []*model.MenuRoleMapping, updated []*model.MenuRoleMapping, deleted []string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ApplyRoleMapping")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]interface{}{"created": created, "updated": updated, "deleted": deleted})
+
+	errChanged := errors.New("role permissions changed while saving, try again")
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, id := range deleted {
+			result := tx.Debug().Exec("DELETE FROM menu_mapping WHERE id = ?", id)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errChanged
+			}
+		}
+
+		for _, req := range updated {
+			result := tx.Debug().Exec("UPDATE menu_mapping SET access_method = ?, updated_at = ?, updated_by = ? WHERE id = ?", req.AccessMethod, req.UpdatedAt, req.UpdatedBy, req.Id)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errChanged
+			}
+		}
+
+		for _, req := range created {
+			query := "INSERT INTO menu_mapping (role_id, menu_id, access_method, created_at, updated_at, created_by, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?)"
+			if err := tx.Debug().Exec(query, req.RoleID, req.MenuID, req.AccessMethod, req.CreatedAt, req.UpdatedAt, req.CreatedBy, req.UpdatedBy).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if err == errChanged {
+			utils.LogEventError(span, err)
+			return model.ThrowError(http.StatusConflict, err)
+		}
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Apply Role Mapping")
 
 	return nil
 }
@@ -220,6 +430,51 @@ func (r *RoleClient) UpdateRoleMapping(ctx context.Context, req *model.MenuRoleM
 	return nil
 }
 
+func (r *RoleClient) GetRoleMappingByID(ctx context.Context, id string) (*model.MenuRoleMapping, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRoleMappingByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var response model.MenuRoleMapping
+
+	query := "SELECT map.id, map.menu_id, menu.menu_name, role.role_name, map.role_id, menu.menu_route, map.access_method, map.created_at, map.updated_at, map.created_by, map.updated_by FROM menu_mapping AS map JOIN menu ON map.menu_id = menu.id JOIN role ON map.role_id = role.id WHERE map.id = ?"
+
+	result := r.db.Debug().Raw(query, id).Scan(&response)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("role mapping not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("role mapping not found"))
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return &response, nil
+}
+
+func (r *RoleClient) DeleteRoleMapping(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteRoleMapping")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	query := "DELETE FROM menu_mapping WHERE id = ?"
+
+	err := r.db.Exec(query, id).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Role Mapping")
+
+	return nil
+}
+
 func (r *RoleClient) UpdateMenu(ctx context.Context, req *model.Menu) error {
 	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateMenu")
 	defer span.Finish()
@@ -228,8 +483,8 @@ func (r *RoleClient) UpdateMenu(ctx context.Context, req *model.Menu) error {
 
 	var args []interface{}
 
-	args = append(args, req.MenuName, req.MenuRoute, req.UpdatedAt, req.UpdatedBy, req.Id)
-	query := "UPDATE menu SET menu_name = ?, menu_route = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+	args = append(args, req.MenuName, req.MenuRoute, req.ParentID, req.SortOrder, req.Icon, req.IsHidden, req.UpdatedAt, req.UpdatedBy, req.Id)
+	query := "UPDATE menu SET menu_name = ?, menu_route = ?, parent_id = NULLIF(?, ''), sort_order = ?, icon = ?, is_hidden = ?, updated_at = ?, updated_by = ? WHERE id = ?"
 
 	err := r.db.Exec(query, args...).Error
 	if err != nil {
@@ -242,20 +497,59 @@ func (r *RoleClient) UpdateMenu(ctx context.Context, req *model.Menu) error {
 	return nil
 }
 
+func (r *RoleClient) CountChildMenu(ctx context.Context, menuID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CountChildMenu")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", menuID)
+
+	var count int64
+
+	query := "SELECT COUNT(*) FROM menu WHERE parent_id = ?"
+
+	err := r.db.Debug().Raw(query, menuID).Scan(&count).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	utils.LogEvent(span, "Response", count)
+
+	return count, nil
+}
+
+// DeleteMenu removes a menu together with the role mappings granting it.
 func (r *RoleClient) DeleteMenu(ctx context.Context, id string) error {
 	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteMenu")
 	defer span.Finish()
 
 	utils.LogEvent(span, "Request", id)
 
-	query := "DELETE FROM menu WHERE id = ?"
+	var rowsAffected int64
 
-	err := r.db.Exec(query, id).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM menu_mapping WHERE menu_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		result := tx.Exec("DELETE FROM menu WHERE id = ?", id)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
 	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1451 { // Row is still referenced
+			utils.LogEventError(span, errors.New("menu is still in use"))
+			return model.ThrowError(http.StatusConflict, errors.New("menu is still in use"))
+		}
 		utils.LogEventError(span, err)
 		return err
 	}
 
+	if rowsAffected == 0 {
+		utils.LogEventError(span, errors.New("menu not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("menu not found"))
+	}
+
 	utils.LogEvent(span, "Response", "Success Delete Menu")
 
 	return nil
@@ -0,0 +1,338 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type InterfaceServiceAccountClient interface {
+	CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error
+	GetAllServiceAccount(ctx context.Context) ([]*model.ServiceAccount, error)
+	GetServiceAccountByID(ctx context.Context, id string) (*model.ServiceAccount, error)
+	UpdateServiceAccount(ctx context.Context, account *model.ServiceAccount) error
+	DeleteServiceAccount(ctx context.Context, id string) error
+
+	CreateApiKey(ctx context.Context, key *model.ApiKey) error
+	GetApiKeyByServiceAccount(ctx context.Context, serviceAccountID string) ([]*model.ApiKey, error)
+	GetApiKeyByID(ctx context.Context, id string) (*model.ApiKey, error)
+	GetApiKeyIdentity(ctx context.Context, keyHash string) (*model.ApiKeyIdentity, error)
+	RevokeApiKey(ctx context.Context, id string, revokedBy string) error
+	UpdateApiKeyLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error
+}
+
+type ServiceAccountClient struct {
+	db *gorm.DB
+}
+
+func NewServiceAccountClient(db *gorm.DB) *ServiceAccountClient {
+	return &ServiceAccountClient{db: db}
+}
+
+func (c *ServiceAccountClient) CreateServiceAccount(ctx context.Context, req *model.ServiceAccount) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateServiceAccount")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.ID, req.Name, req.Description, req.RoleID, req.InstitutionID, req.IsActive, req.CreatedAt, req.CreatedBy, req.UpdatedAt, req.UpdatedBy)
+
+	query := "INSERT INTO service_account (id, name, description, role_id, institution_id, is_active, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		if mysqlErr, ok := result.Error.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			utils.LogEventError(span, errors.New("service account name already exists"))
+			return model.ThrowError(http.StatusBadRequest, errors.New("service account name already exists"))
+		}
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Service Account")
+
+	return nil
+}
+
+func (c *ServiceAccountClient) GetAllServiceAccount(ctx context.Context) ([]*model.ServiceAccount, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetAllServiceAccount")
+	defer span.Finish()
+
+	var response []*model.ServiceAccount
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	query := "SELECT * FROM service_account WHERE 1 = 1" + tenant + " ORDER BY name"
+	result := c.db.Debug().WithContext(ctx).Raw(query, tenantArgs...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *ServiceAccountClient) GetServiceAccountByID(ctx context.Context, id string) (*model.ServiceAccount, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetServiceAccountByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var response model.ServiceAccount
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	query := "SELECT * FROM service_account WHERE id = ?" + tenant
+	result := c.db.Debug().WithContext(ctx).Raw(query, append([]interface{}{id}, tenantArgs...)...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("service account not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("service account not found"))
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return &response, nil
+}
+
+func (c *ServiceAccountClient) UpdateServiceAccount(ctx context.Context, req *model.ServiceAccount) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateServiceAccount")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.Name, req.Description, req.RoleID, req.InstitutionID, req.IsActive, req.UpdatedAt, req.UpdatedBy, req.ID)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+	args = append(args, tenantArgs...)
+
+	query := "UPDATE service_account SET name = ?, description = ?, role_id = ?, institution_id = ?, is_active = ?, updated_at = ?, updated_by = ? WHERE id = ?" + tenant
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		if mysqlErr, ok := result.Error.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			utils.LogEventError(span, errors.New("service account name already exists"))
+			return model.ThrowError(http.StatusBadRequest, errors.New("service account name already exists"))
+		}
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("service account not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("service account not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Service Account")
+
+	return nil
+}
+
+// DeleteServiceAccount removes a service account together with all of its API keys.
+func (c *ServiceAccountClient) DeleteServiceAccount(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteServiceAccount")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+	args := append([]interface{}{id}, tenantArgs...)
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Debug().Exec("DELETE FROM api_key WHERE service_account_id IN (SELECT id FROM service_account WHERE id = ?"+tenant+")", args...).Error; err != nil {
+			return err
+		}
+
+		result := tx.Debug().Exec("DELETE FROM service_account WHERE id = ?"+tenant, args...)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return model.ThrowError(http.StatusNotFound, errors.New("service account not found"))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		utils.LogEventError(span, err)
+		if _, ok := err.(*model.ErrorResponse); ok {
+			return err
+		}
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Service Account")
+
+	return nil
+}
+
+func (c *ServiceAccountClient) CreateApiKey(ctx context.Context, req *model.ApiKey) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateApiKey")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	var args []interface{}
+	args = append(args, req.ID, req.KeyPrefix, req.KeyHash, req.ExpiredAt, req.CreatedAt, req.CreatedBy, req.ServiceAccountID)
+	args = append(args, tenantArgs...)
+
+	query := "INSERT INTO api_key (id, service_account_id, key_prefix, key_hash, expired_at, created_at, created_by) SELECT ?, id, ?, ?, ?, ?, ? FROM service_account WHERE id = ?" + tenant
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("service account not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("service account not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Api Key")
+
+	return nil
+}
+
+func (c *ServiceAccountClient) GetApiKeyByServiceAccount(ctx context.Context, serviceAccountID string) ([]*model.ApiKey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetApiKeyByServiceAccount")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", serviceAccountID)
+
+	var response []*model.ApiKey
+
+	tenant, tenantArgs := tenantCondition(ctx, "s.institution_id")
+
+	query := "SELECT k.* FROM api_key k JOIN service_account s ON s.id = k.service_account_id WHERE k.service_account_id = ?" + tenant + " ORDER BY k.created_at DESC"
+	result := c.db.Debug().WithContext(ctx).Raw(query, append([]interface{}{serviceAccountID}, tenantArgs...)...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *ServiceAccountClient) GetApiKeyByID(ctx context.Context, id string) (*model.ApiKey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetApiKeyByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var response model.ApiKey
+
+	tenant, tenantArgs := tenantCondition(ctx, "s.institution_id")
+
+	query := "SELECT k.* FROM api_key k JOIN service_account s ON s.id = k.service_account_id WHERE k.id = ?" + tenant
+	result := c.db.Debug().WithContext(ctx).Raw(query, append([]interface{}{id}, tenantArgs...)...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("api key not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("api key not found"))
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return &response, nil
+}
+
+// GetApiKeyIdentity looks up an API key by its hash together with its service account.
+func (c *ServiceAccountClient) GetApiKeyIdentity(ctx context.Context, keyHash string) (*model.ApiKeyIdentity, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetApiKeyIdentity")
+	defer span.Finish()
+
+	var response model.ApiKeyIdentity
+
+	query := `SELECT k.id, k.service_account_id, s.name, s.role_id, s.institution_id, s.is_active, k.expired_at, k.revoked_at
+		FROM api_key k JOIN service_account s ON s.id = k.service_account_id
+		WHERE k.key_hash = ?`
+	result := c.db.Debug().WithContext(ctx).Raw(query, keyHash).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("api key not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("api key not found"))
+	}
+
+	utils.LogEvent(span, "Response", response.ID)
+
+	return &response, nil
+}
+
+func (c *ServiceAccountClient) RevokeApiKey(ctx context.Context, id string, revokedBy string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: RevokeApiKey")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	var args []interface{}
+	args = append(args, time.Now(), revokedBy, id)
+	args = append(args, tenantArgs...)
+
+	query := "UPDATE api_key SET revoked_at = ?, revoked_by = ? WHERE id = ? AND revoked_at IS NULL AND service_account_id IN (SELECT id FROM service_account WHERE 1 = 1" + tenant + ")"
+	result := c.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("api key not found or already revoked"))
+		return model.ThrowError(http.StatusNotFound, errors.New("api key not found or already revoked"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Revoke Api Key")
+
+	return nil
+}
+
+func (c *ServiceAccountClient) UpdateApiKeyLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateApiKeyLastUsed")
+	defer span.Finish()
+
+	query := "UPDATE api_key SET last_used_at = ? WHERE id = ?"
+	result := c.db.Debug().WithContext(ctx).Exec(query, lastUsedAt, id)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	return nil
+}
@@ -70,7 +70,7 @@ func (c *StorageClient) DeleteObject(ctx context.Context, bucket string, prefix
 		Prefix: aws.String(prefix),
 	}
 
-	logrus.Printf("Deleting objects under prefix %s\n", bucket, prefix)
+	logrus.Printf("Deleting objects in bucket %s under prefix %s\n", bucket, prefix)
 
 	for {
 		// Get a batch of objects
@@ -125,10 +125,10 @@ func (c *StorageClient) StoreFileData(ctx context.Context, tx *gorm.DB, req *mod
 	defer span.Finish()
 
 	var args []interface{}
-	args = append(args, req.Username, req.Bucket, time.Now())
+	args = append(args, req.Username, req.Username, req.Bucket, time.Now())
 
 	var result *gorm.DB
-	query := "INSERT INTO face_datasets (username, dataset, created_at) VALUES (?, ?, ?)"
+	query := "INSERT INTO face_datasets (username, institution_id, dataset, created_at) VALUES (?, (SELECT institution_id FROM users WHERE username = ?), ?, ?)"
 	if tx != nil {
 		result = tx.Debug().WithContext(ctx).Exec(query, args...)
 	} else {
@@ -148,12 +148,14 @@ func (c *StorageClient) DeleteDatasetDB(ctx context.Context, tx *gorm.DB, userna
 	defer span.Finish()
 
 	var result *gorm.DB
-	query := "DELETE FROM face_datasets WHERE username = ?"
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+	query := "DELETE FROM face_datasets WHERE username = ?" + tenant
+	args := append([]interface{}{username}, tenantArgs...)
 
 	if tx != nil {
-		result = tx.Debug().WithContext(ctx).Exec(query, username)
+		result = tx.Debug().WithContext(ctx).Exec(query, args...)
 	} else {
-		result = c.db.Debug().WithContext(ctx).Exec(query, username)
+		result = c.db.Debug().WithContext(ctx).Exec(query, args...)
 	}
 
 	if result.Error != nil {
@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/app/utils"
+)
+
+// tenantCondition returns the condition limiting column to the institution the caller is
+// limited to, to be appended to a WHERE clause, or nothing for callers that may act across
+// institutions. Rows of other institutions then look like they do not exist.
+func tenantCondition(ctx context.Context, column string) (string, []interface{}) {
+	institutionID, ok := utils.GetTenant(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	return " AND COALESCE(" + column + ", '') = ?", []interface{}{institutionID}
+}
@@ -6,21 +6,48 @@ import (
 	"face-recognition-svc/app/config"
 	"face-recognition-svc/app/model"
 	"face-recognition-svc/app/utils"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// defaultRefreshExpiry is how many hours a refresh token stays valid when RefreshExpiry is
+// not configured.
+const defaultRefreshExpiry = 7 * 24
+
+// passwordHistoryRetention is how many previous password hashes are kept per user, the
+// PASSWORD_HISTORY parameter can only look back this far.
+const passwordHistoryRetention = 24
+
 type InterfaceUserClient interface {
 	CreateNewUser(ctx context.Context, user *model.User) error
-	GetUserDetail(ctx context.Context, username string) (*model.User, error)
-	CreateAccessToken(ctx context.Context, user *model.User, isLogout bool, menuMapping map[string]string) (t string, expired int64, err error)
-	GetAllUser(ctx context.Context) ([]*model.User, error)
+	GetUserDetail(ctx context.Context, username string) (*model.UserDetail, error)
+	GetUserCredential(ctx context.Context, username string) (*model.User, error)
+	CreateAccessToken(ctx context.Context, user *model.UserDetail, sessionID string, amr []string) (t string, expired int64, err error)
+	CreateRefreshToken(ctx context.Context, user *model.UserDetail, sessionID string, tokenID string, amr []string) (t string, expired int64, err error)
+	CreateImpersonationToken(ctx context.Context, user *model.UserDetail, sessionID string, amr []string, actor *model.ActorClaim, expiredAt time.Time) (t string, err error)
+	ParseRefreshToken(ctx context.Context, token string) (*model.JwtRefreshClaims, error)
+	GetAllUser(ctx context.Context) ([]*model.UserDetail, error)
 	GetInstitutionList(ctx context.Context) ([]string, error)
+	UpdateUser(ctx context.Context, user *model.RequestUpdateUser) error
+	UpdateProfile(ctx context.Context, username string, request *model.RequestUpdateProfile) error
+	UpdateUserStatus(ctx context.Context, request *model.RequestUserStatus) error
+	UpdatePassword(ctx context.Context, username string, password string) error
+	RehashPassword(ctx context.Context, username string, oldHash string, newHash string) error
+	GetPasswordHistory(ctx context.Context, username string, limit int) ([]string, error)
+	DeleteUser(ctx context.Context, username string) error
+
+	GetUserByEmail(ctx context.Context, email string) (*model.UserDetail, error)
+	UpdateUserRole(ctx context.Context, username string, roleID string, updatedBy string) error
+	GetUserIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error)
+	CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) error
 }
 
 type UserClient struct {
@@ -41,10 +68,15 @@ func (r *UserClient) CreateNewUser(ctx context.Context, req *model.User) error {
 
 	utils.LogEvent(span, "Request", req)
 
+	authSource := req.AuthSource
+	if authSource == "" {
+		authSource = model.AuthSourceLocal
+	}
+
 	var args []interface{}
-	args = append(args, req.Username, req.Email, req.Password, req.Fullname, req.Shortname, req.RoleID, req.InstitutionID, time.Now())
+	args = append(args, req.Username, req.Email, req.Password, authSource, req.Fullname, req.Shortname, req.RoleID, req.InstitutionID, time.Now())
 
-	query := "INSERT INTO users (username, email, password, fullname, shortname, role_id, institution_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	query := "INSERT INTO users (username, email, password, auth_source, fullname, shortname, role_id, institution_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)"
 	result := r.db.Debug().WithContext(ctx).Exec(query, args...)
 
 	if result.Error != nil {
@@ -53,6 +85,9 @@ func (r *UserClient) CreateNewUser(ctx context.Context, req *model.User) error {
 			case 1062: // Duplicate entry
 				utils.LogEventError(span, errors.New("username or email already exists"))
 				return model.ThrowError(http.StatusBadRequest, errors.New("username or email already exists"))
+			case 1452: // Foreign key constraint
+				utils.LogEventError(span, errors.New("institution not found"))
+				return model.ThrowError(http.StatusBadRequest, errors.New("institution not found"))
 			}
 		}
 		utils.LogEventError(span, result.Error)
@@ -62,12 +97,44 @@ func (r *UserClient) CreateNewUser(ctx context.Context, req *model.User) error {
 	return nil
 }
 
-func (r *UserClient) GetUserDetail(ctx context.Context, username string) (*model.User, error) {
+const userDetailColumns = "username, email, fullname, shortname, role_id, COALESCE(institution_id, '') AS institution_id, is_active, auth_source, created_at"
+
+func (r *UserClient) GetUserDetail(ctx context.Context, username string) (*model.UserDetail, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Client: GetUserDetail")
 	defer span.Finish()
 
 	utils.LogEvent(span, "Request", username)
 
+	var user model.UserDetail
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	query := "SELECT " + userDetailColumns + " FROM users WHERE username = ?" + tenant
+	result := r.db.Debug().WithContext(ctx).Raw(query, append([]interface{}{username}, tenantArgs...)...).Scan(&user)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("user not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+
+	utils.LogEvent(span, "Response", user)
+
+	return &user, nil
+}
+
+// GetUserCredential loads the full users row including the password hash, it is only meant
+// for verifying credentials and must not be returned to callers.
+func (r *UserClient) GetUserCredential(ctx context.Context, username string) (*model.User, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetUserCredential")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
 	var user model.User
 
 	query := "SELECT * FROM users WHERE username = ?"
@@ -80,7 +147,7 @@ func (r *UserClient) GetUserDetail(ctx context.Context, username string) (*model
 
 	if result.RowsAffected == 0 {
 		utils.LogEventError(span, errors.New("user not found"))
-		return nil, model.ThrowError(http.StatusBadRequest, errors.New("user not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("user not found"))
 	}
 
 	utils.LogEvent(span, "Response", user)
@@ -88,49 +155,143 @@ func (r *UserClient) GetUserDetail(ctx context.Context, username string) (*model
 	return &user, nil
 }
 
-func (r *UserClient) CreateAccessToken(ctx context.Context, user *model.User, isLogout bool, menuMapping map[string]string) (t string, expired int64, err error) {
+func (r *UserClient) CreateAccessToken(ctx context.Context, user *model.UserDetail, sessionID string, amr []string) (t string, expired int64, err error) {
 	span, _ := utils.SpanFromContext(ctx, "Client: CreateAccessToken")
 	defer span.Finish()
 
 	utils.LogEvent(span, "Request", user)
 
 	ExpireCount, _ := strconv.Atoi(r.cfg.Auth.AccessExpiry)
-	if isLogout {
-		ExpireCount = 0
-	}
 
 	utils.LogEvent(span, "Expiry", ExpireCount)
 
 	exp := time.Now().Add(time.Hour * time.Duration(ExpireCount))
 	claims := &model.JwtCustomClaims{
-		Name: user.Username,
-		Role: user.RoleID,
+		Name:        user.Username,
+		Role:        user.RoleID,
+		Institution: user.InstitutionID,
+		SessionID:   sessionID,
+		Amr:         amr,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(exp),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
-		MenuMapping: menuMapping,
 	}
 	expired = exp.Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err = token.SignedString([]byte(r.cfg.Auth.AccessSecret))
+	t, err = utils.Keys.Sign(claims)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return "", 0, err
 	}
 
-	utils.LogEvent(span, "Token", t)
+	return t, expired, nil
+}
+
+// CreateImpersonationToken signs an access token for user on behalf of actor. It is never
+// paired with a refresh token, so it cannot outlive expiredAt.
+func (r *UserClient) CreateImpersonationToken(ctx context.Context, user *model.UserDetail, sessionID string, amr []string, actor *model.ActorClaim, expiredAt time.Time) (t string, err error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: CreateImpersonationToken")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]string{"username": user.Username, "actor": actor.Subject})
+
+	claims := &model.JwtCustomClaims{
+		Name:        user.Username,
+		Role:        user.RoleID,
+		Institution: user.InstitutionID,
+		SessionID:   sessionID,
+		Amr:         amr,
+		Act:         actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(expiredAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	t, err = utils.Keys.Sign(claims)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return "", err
+	}
+
+	return t, nil
+}
+
+func (r *UserClient) CreateRefreshToken(ctx context.Context, user *model.UserDetail, sessionID string, tokenID string, amr []string) (t string, expired int64, err error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: CreateRefreshToken")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", sessionID)
+
+	ExpireCount := defaultRefreshExpiry
+	if r.cfg.Auth.RefreshExpiry != "" {
+		ExpireCount, err = strconv.Atoi(r.cfg.Auth.RefreshExpiry)
+		if err != nil || ExpireCount <= 0 {
+			err = fmt.Errorf("invalid refresh token expiry %q, expected a positive number of hours", r.cfg.Auth.RefreshExpiry)
+			utils.LogEventError(span, err)
+			return "", 0, err
+		}
+	}
+
+	utils.LogEvent(span, "Expiry", ExpireCount)
+
+	exp := time.Now().Add(time.Hour * time.Duration(ExpireCount))
+	claims := &model.JwtRefreshClaims{
+		Name:      user.Username,
+		SessionID: sessionID,
+		Amr:       amr,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(exp),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	expired = exp.Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, err = token.SignedString([]byte(r.cfg.Auth.RefreshSecret))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return "", 0, err
+	}
 
 	return t, expired, nil
 }
 
-func (r *UserClient) GetAllUser(ctx context.Context) ([]*model.User, error) {
+func (r *UserClient) ParseRefreshToken(ctx context.Context, token string) (*model.JwtRefreshClaims, error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: ParseRefreshToken")
+	defer span.Finish()
+
+	claims := &model.JwtRefreshClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(r.cfg.Auth.RefreshSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid refresh token"))
+	}
+
+	if claims.ID == "" || claims.SessionID == "" {
+		utils.LogEventError(span, errors.New("refresh token is missing session"))
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid refresh token"))
+	}
+
+	utils.LogEvent(span, "Response", claims.SessionID)
+
+	return claims, nil
+}
+
+func (r *UserClient) GetAllUser(ctx context.Context) ([]*model.UserDetail, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Client: GetAllUser")
 	defer span.Finish()
 
-	var response []*model.User
+	var response []*model.UserDetail
 
-	query := "SELECT * FROM users"
-	result := r.db.Debug().WithContext(ctx).Raw(query).Scan(&response)
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	query := "SELECT " + userDetailColumns + " FROM users WHERE 1 = 1" + tenant
+	result := r.db.Debug().WithContext(ctx).Raw(query, tenantArgs...).Scan(&response)
 
 	if result.Error != nil {
 		utils.LogEventError(span, result.Error)
@@ -148,8 +309,10 @@ func (r *UserClient) GetInstitutionList(ctx context.Context) ([]string, error) {
 
 	var response []string
 
-	query := "SELECT DISTINCT institution_id FROM users"
-	result := r.db.Debug().WithContext(ctx).Raw(query).Scan(&response)
+	tenant, tenantArgs := tenantCondition(ctx, "id")
+
+	query := "SELECT id FROM institution WHERE status = ?" + tenant + " ORDER BY name"
+	result := r.db.Debug().WithContext(ctx).Raw(query, append([]interface{}{model.InstitutionStatusActive}, tenantArgs...)...).Scan(&response)
 
 	if result.Error != nil {
 		utils.LogEventError(span, result.Error)
@@ -160,3 +323,375 @@ func (r *UserClient) GetInstitutionList(ctx context.Context) ([]string, error) {
 
 	return response, nil
 }
+
+func (r *UserClient) UpdateUser(ctx context.Context, req *model.RequestUpdateUser) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateUser")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.Email, req.Fullname, req.Shortname, req.RoleID, req.InstitutionID, req.UpdatedAt, req.UpdatedBy, req.Username)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+	args = append(args, tenantArgs...)
+
+	query := "UPDATE users SET email = ?, fullname = ?, shortname = ?, role_id = ?, institution_id = NULLIF(?, ''), updated_at = ?, updated_by = ? WHERE username = ?" + tenant
+	result := r.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		if mysqlErr, ok := result.Error.(*mysql.MySQLError); ok {
+			switch mysqlErr.Number {
+			case 1062: // Duplicate entry
+				utils.LogEventError(span, errors.New("email already exists"))
+				return model.ThrowError(http.StatusBadRequest, errors.New("email already exists"))
+			}
+		}
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("user not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Update User")
+
+	return nil
+}
+
+func (r *UserClient) UpdateProfile(ctx context.Context, username string, req *model.RequestUpdateProfile) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateProfile")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.Email, req.Fullname, req.Shortname, req.UpdatedAt, req.UpdatedBy, username)
+
+	query := "UPDATE users SET email = ?, fullname = ?, shortname = ?, updated_at = ?, updated_by = ? WHERE username = ?"
+	result := r.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		if mysqlErr, ok := result.Error.(*mysql.MySQLError); ok {
+			switch mysqlErr.Number {
+			case 1062: // Duplicate entry
+				utils.LogEventError(span, errors.New("email already exists"))
+				return model.ThrowError(http.StatusBadRequest, errors.New("email already exists"))
+			}
+		}
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("user not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Profile")
+
+	return nil
+}
+
+func (r *UserClient) UpdateUserStatus(ctx context.Context, req *model.RequestUserStatus) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateUserStatus")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	var args []interface{}
+	args = append(args, req.IsActive, req.UpdatedAt, req.UpdatedBy, req.Username)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+	args = append(args, tenantArgs...)
+
+	query := "UPDATE users SET is_active = ?, updated_at = ?, updated_by = ? WHERE username = ?" + tenant
+	result := r.db.Debug().WithContext(ctx).Exec(query, args...)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("user not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Update User Status")
+
+	return nil
+}
+
+// UpdatePassword replaces the password of a user, keeping the previous hash in the
+// password history so it cannot be chosen again too soon.
+func (r *UserClient) UpdatePassword(ctx context.Context, username string, password string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdatePassword")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		query := "INSERT INTO password_history (username, password, created_at) SELECT username, password, ? FROM users WHERE username = ? AND password <> ?"
+		if err := tx.Debug().Exec(query, now, username, model.UnusablePassword).Error; err != nil {
+			return err
+		}
+
+		result := tx.Debug().Exec("UPDATE users SET password = ?, updated_at = ? WHERE username = ?", password, now, username)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+		}
+
+		query = "DELETE FROM password_history WHERE username = ? AND id NOT IN (SELECT id FROM (SELECT id FROM password_history WHERE username = ? ORDER BY id DESC LIMIT ?) AS recent)"
+		return tx.Debug().Exec(query, username, username, passwordHistoryRetention).Error
+	})
+
+	if err != nil {
+		utils.LogEventError(span, err)
+		if _, ok := err.(*model.ErrorResponse); ok {
+			return err
+		}
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Password")
+
+	return nil
+}
+
+// RehashPassword swaps a password hash for a stronger hash of the same password. It does
+// nothing when the password was changed in the meantime.
+func (r *UserClient) RehashPassword(ctx context.Context, username string, oldHash string, newHash string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: RehashPassword")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	query := "UPDATE users SET password = ? WHERE username = ? AND password = ?"
+	result := r.db.Debug().WithContext(ctx).Exec(query, newHash, username, oldHash)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Rehash Password")
+
+	return nil
+}
+
+// GetPasswordHistory returns the hashes of the latest previous passwords of a user, newest
+// first.
+func (r *UserClient) GetPasswordHistory(ctx context.Context, username string, limit int) ([]string, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetPasswordHistory")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	var response []string
+
+	query := "SELECT password FROM password_history WHERE username = ? ORDER BY id DESC LIMIT ?"
+	result := r.db.Debug().WithContext(ctx).Raw(query, username, limit).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", len(response))
+
+	return response, nil
+}
+
+func (r *UserClient) DeleteUser(ctx context.Context, username string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteUser")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	var rowsAffected int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := "DELETE FROM users WHERE username = ?" + tenant
+		result := tx.Debug().Exec(query, append([]interface{}{username}, tenantArgs...)...)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		rowsAffected = result.RowsAffected
+		if rowsAffected == 0 {
+			return nil
+		}
+
+		// Rows keyed by username would otherwise be inherited by a user created later with
+		// the same username, an external identity would even log straight into it.
+		for _, query := range []string{
+			"DELETE FROM user_role WHERE username = ?",
+			"DELETE FROM user_identity WHERE username = ?",
+			"DELETE FROM user_mfa WHERE username = ?",
+			"DELETE FROM user_mfa_recovery_code WHERE username = ?",
+			"DELETE FROM user_passkey WHERE username = ?",
+			"DELETE FROM password_history WHERE username = ?",
+		} {
+			if err := tx.Debug().Exec(query, username).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			switch mysqlErr.Number {
+			case 1451: // Row is still referenced
+				utils.LogEventError(span, errors.New("user still has related data"))
+				return model.ThrowError(http.StatusConflict, errors.New("user still has related data"))
+			}
+		}
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		utils.LogEventError(span, errors.New("user not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete User")
+
+	return nil
+}
+
+func (r *UserClient) GetUserByEmail(ctx context.Context, email string) (*model.UserDetail, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetUserByEmail")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", email)
+
+	var user model.UserDetail
+
+	query := "SELECT " + userDetailColumns + " FROM users WHERE email = ?"
+	result := r.db.Debug().WithContext(ctx).Raw(query, email).Scan(&user)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("user not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+
+	utils.LogEvent(span, "Response", user)
+
+	return &user, nil
+}
+
+func (r *UserClient) UpdateUserRole(ctx context.Context, username string, roleID string, updatedBy string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateUserRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]string{"username": username, "role_id": roleID})
+
+	query := "UPDATE users SET role_id = ?, updated_at = ?, updated_by = ? WHERE username = ?"
+	result := r.db.Debug().WithContext(ctx).Exec(query, roleID, time.Now(), updatedBy, username)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update User Role")
+
+	return nil
+}
+
+// GetUserIdentity returns the user linked to an external identity, or nil when there is none.
+func (r *UserClient) GetUserIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetUserIdentity")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]string{"provider": provider, "subject": subject})
+
+	var identity model.UserIdentity
+
+	query := "SELECT provider, subject, username FROM user_identity WHERE provider = ? AND subject = ?"
+	result := r.db.Debug().WithContext(ctx).Raw(query, provider, subject).Scan(&identity)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	utils.LogEvent(span, "Response", identity)
+
+	return &identity, nil
+}
+
+func (r *UserClient) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateUserIdentity")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", identity)
+
+	query := "INSERT INTO user_identity (provider, subject, username, created_at) VALUES (?, ?, ?, ?)"
+	result := r.db.Debug().WithContext(ctx).Exec(query, identity.Provider, identity.Subject, identity.Username, time.Now())
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	return nil
+}
+
+// CreateUserWithIdentity provisions a user for an external identity in one transaction.
+func (r *UserClient) CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateUserWithIdentity")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", identity)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var args []interface{}
+		args = append(args, user.Username, user.Email, user.Password, user.AuthSource, user.Fullname, user.Shortname, user.RoleID, user.InstitutionID, now)
+
+		query := "INSERT INTO users (username, email, password, auth_source, fullname, shortname, role_id, institution_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)"
+		if err := tx.Debug().Exec(query, args...).Error; err != nil {
+			return err
+		}
+
+		query = "INSERT INTO user_identity (provider, subject, username, created_at) VALUES (?, ?, ?, ?)"
+		return tx.Debug().Exec(query, identity.Provider, identity.Subject, identity.Username, now).Error
+	})
+
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			utils.LogEventError(span, errors.New("username or email already exists"))
+			return model.ThrowError(http.StatusConflict, errors.New("username or email already exists"))
+		}
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create User With Identity")
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type InterfaceUserRoleClient interface {
+	GetUserRole(ctx context.Context, username string) ([]*model.UserRole, error)
+	GetUserRoleByID(ctx context.Context, id string) (*model.UserRole, error)
+	CreateUserRole(ctx context.Context, grant *model.UserRole) error
+	DeleteUserRole(ctx context.Context, id string) error
+}
+
+type UserRoleClient struct {
+	db *gorm.DB
+}
+
+func NewUserRoleClient(db *gorm.DB) *UserRoleClient {
+	return &UserRoleClient{db: db}
+}
+
+// GetUserRole returns the role grants of a user that have not expired yet, oldest first.
+func (c *UserRoleClient) GetUserRole(ctx context.Context, username string) ([]*model.UserRole, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetUserRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	tenant, tenantArgs := tenantCondition(ctx, "u.institution_id")
+
+	var args []interface{}
+	args = append(args, username, time.Now())
+	args = append(args, tenantArgs...)
+
+	var response []*model.UserRole
+
+	query := "SELECT ur.id, ur.username, ur.role_id, r.role_name, ur.expires_at, ur.created_at, ur.created_by FROM user_role ur JOIN users u ON u.username = ur.username JOIN role r ON r.id = ur.role_id WHERE ur.username = ? AND (ur.expires_at IS NULL OR ur.expires_at > ?)" + tenant + " ORDER BY ur.created_at ASC, ur.id ASC"
+	result := c.db.Debug().WithContext(ctx).Raw(query, args...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *UserRoleClient) GetUserRoleByID(ctx context.Context, id string) (*model.UserRole, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetUserRoleByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	tenant, tenantArgs := tenantCondition(ctx, "u.institution_id")
+
+	var response model.UserRole
+
+	query := "SELECT ur.id, ur.username, ur.role_id, r.role_name, ur.expires_at, ur.created_at, ur.created_by FROM user_role ur JOIN users u ON u.username = ur.username JOIN role r ON r.id = ur.role_id WHERE ur.id = ?" + tenant
+	result := c.db.Debug().WithContext(ctx).Raw(query, append([]interface{}{id}, tenantArgs...)...).Scan(&response)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("role grant not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("role grant not found"))
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return &response, nil
+}
+
+// CreateUserRole grants a role to an existing user. Expired grants of the user are removed
+// first, so that an expired grant of the same role can be given again.
+func (c *UserRoleClient) CreateUserRole(ctx context.Context, req *model.UserRole) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateUserRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	tenant, tenantArgs := tenantCondition(ctx, "institution_id")
+
+	var rowsAffected int64
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Debug().Exec("DELETE FROM user_role WHERE username = ? AND expires_at <= ?", req.Username, time.Now()).Error; err != nil {
+			return err
+		}
+
+		var args []interface{}
+		args = append(args, req.ID, req.RoleID, req.ExpiresAt, req.CreatedAt, req.CreatedBy, req.Username)
+		args = append(args, tenantArgs...)
+
+		query := "INSERT INTO user_role (id, username, role_id, expires_at, created_at, created_by) SELECT ?, username, ?, ?, ?, ? FROM users WHERE username = ?" + tenant
+		result := tx.Debug().Exec(query, args...)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 { // Duplicate entry
+			utils.LogEventError(span, errors.New("role is already granted to the user"))
+			return model.ThrowError(http.StatusConflict, errors.New("role is already granted to the user"))
+		}
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		utils.LogEventError(span, errors.New("user not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Create User Role")
+
+	return nil
+}
+
+func (c *UserRoleClient) DeleteUserRole(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteUserRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	result := c.db.Debug().WithContext(ctx).Exec("DELETE FROM user_role WHERE id = ?", id)
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("role grant not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("role grant not found"))
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete User Role")
+
+	return nil
+}
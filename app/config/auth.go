@@ -4,4 +4,80 @@ type Auth struct {
 	AccessSecret  string `yaml:"accessSecret"`
 	RefreshSecret string `yaml:"refreshSecret"`
 	AccessExpiry  string `yaml:"accessExpiry"`
+	RefreshExpiry string `yaml:"refreshExpiry" desc:"hours"`
+
+	// ActiveKeyID selects the key in SigningKeys used to sign new access tokens. When it is
+	// empty access tokens are signed with HS256 and AccessSecret as before. Once it is set,
+	// HS256 tokens without a kid are only accepted until LegacyTokensUntil, which should be
+	// the time of the switch plus AccessExpiry.
+	ActiveKeyID       string       `yaml:"activeKeyId"`
+	SigningKeys       []SigningKey `yaml:"signingKeys"`
+	LegacyTokensUntil string       `yaml:"legacyTokensUntil" desc:"RFC 3339 time, HS256 tokens are refused after a switch to activeKeyId when empty"`
+
+	Password Password `yaml:"password"`
+	LDAP     LDAP     `yaml:"ldap"`
+	WebAuthn WebAuthn `yaml:"webauthn"`
+}
+
+// Password configures how local passwords are hashed and which are refused. Changing the
+// algorithm or raising its parameters is safe: existing hashes keep verifying and are
+// replaced on the user's next successful login. Length, character class and reuse rules
+// are PASSWORD_* parameters so they can be tuned at runtime.
+type Password struct {
+	Algorithm     string `yaml:"algorithm" default:"bcrypt" desc:"bcrypt or argon2id"`
+	BcryptCost    int    `yaml:"bcryptCost" default:"10"`
+	Argon2Memory  uint32 `yaml:"argon2Memory" default:"65536" desc:"KiB"`
+	Argon2Time    uint32 `yaml:"argon2Time" default:"3"`
+	Argon2Threads uint8  `yaml:"argon2Threads" default:"2"`
+	BlocklistFile string `yaml:"blocklistFile" desc:"common passwords, one per line, compared case-insensitively"`
+}
+
+// SigningKey is an asymmetric key published in the JWKS under ID.
+//
+// To rotate, add the new key and point ActiveKeyID at it; keep the previous key, its
+// private key may be dropped, until every token it signed has expired, then remove it.
+type SigningKey struct {
+	ID             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm" desc:"RS256 or EdDSA"`
+	PrivateKey     string `yaml:"privateKey" desc:"PEM encoded private key"`
+	PrivateKeyFile string `yaml:"privateKeyFile"`
+	PublicKey      string `yaml:"publicKey" desc:"PEM encoded public key"`
+	PublicKeyFile  string `yaml:"publicKeyFile"`
+}
+
+// LDAP configures password login against a directory such as Active Directory. Users with
+// auth_source ldap are checked here instead of users.password; local accounts keep
+// working, so break-glass admins are never locked out by a directory outage.
+type LDAP struct {
+	Enabled            bool   `yaml:"enabled"`
+	URL                string `yaml:"url" desc:"ldap://host:389 or ldaps://host:636"`
+	StartTLS           bool   `yaml:"startTls"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+	CAFile             string `yaml:"caFile" desc:"PEM encoded CAs the server certificate is verified with, the system pool when empty"`
+	BindDN             string `yaml:"bindDn" desc:"service account used to search for users"`
+	BindPassword       string `yaml:"bindPassword"`
+	BaseDN             string `yaml:"baseDn"`
+	UserFilter         string `yaml:"userFilter" default:"(sAMAccountName=%s)"`
+	EmailAttribute     string `yaml:"emailAttribute" default:"mail"`
+	NameAttribute      string `yaml:"nameAttribute" default:"displayName"`
+	GroupAttribute     string `yaml:"groupAttribute" default:"memberOf"`
+
+	// AutoProvision creates a user with auth_source ldap on the first successful bind. The
+	// role comes from the first entry in GroupRoles the user is a member of, or DefaultRoleID.
+	AutoProvision bool            `yaml:"autoProvision"`
+	InstitutionID string          `yaml:"institutionId"`
+	DefaultRoleID string          `yaml:"defaultRoleId"`
+	GroupRoles    []LDAPGroupRole `yaml:"groupRoles"`
+}
+
+type LDAPGroupRole struct {
+	Group  string `yaml:"group" desc:"group DN as listed in groupAttribute"`
+	RoleID string `yaml:"roleId"`
+}
+
+// WebAuthn configures passkey login. It is disabled while RPID is empty.
+type WebAuthn struct {
+	RPID          string   `yaml:"rpId" desc:"domain of the frontend without scheme or port"`
+	RPDisplayName string   `yaml:"rpDisplayName" default:"Face Recognition"`
+	RPOrigins     []string `yaml:"rpOrigins" desc:"fully qualified frontend origins, e.g. https://face.example.com"`
 }
@@ -4,12 +4,18 @@ type Config struct {
 	Listener struct {
 		Host string
 		Port int
+
+		// TrustedProxies are the addresses or CIDR ranges of the load balancers in front of the
+		// service. The client IP is taken from X-Forwarded-For only when the request comes
+		// from one of them; without any, the address of the connection is used.
+		TrustedProxies []string `yaml:"trustedProxies" desc:"e.g. 10.0.0.0/8"`
 	}
 	DatabaseProfile struct {
 		Database Database `yaml:"database"`
 		Timeout  int      `yaml:"timeout" default:"30000"`
 	} `yaml:"databaseProfile"`
 	Auth         Auth        `yaml:"auth"`
+	OIDC         OIDC        `yaml:"oidc"`
 	Redis        Redis       `yaml:"redis"`
 	Jaeger       Jaeger      `yaml:"jaeger"`
 	MinioProfile MinioS3     `yaml:"minioProfile"`
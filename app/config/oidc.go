@@ -0,0 +1,34 @@
+package config
+
+// OIDC lists the OpenID Connect identity providers users can log in with, one per
+// institution.
+type OIDC struct {
+	Providers []OIDCProvider `yaml:"providers"`
+}
+
+type OIDCProvider struct {
+	InstitutionID string   `yaml:"institutionId"`
+	Issuer        string   `yaml:"issuer" desc:"discovery is fetched from <issuer>/.well-known/openid-configuration"`
+	ClientID      string   `yaml:"clientId"`
+	ClientSecret  string   `yaml:"clientSecret"`
+	RedirectURI   string   `yaml:"redirectUri"`
+	Scopes        []string `yaml:"scopes" default:"openid,email,profile"`
+
+	UsernameClaim string `yaml:"usernameClaim" default:"preferred_username"`
+	EmailClaim    string `yaml:"emailClaim" default:"email"`
+	GroupsClaim   string `yaml:"groupsClaim" default:"groups"`
+
+	// AutoProvision creates a user on first login. The role comes from the first matching
+	// rule in RoleRules, or DefaultRoleID when none matches.
+	AutoProvision bool           `yaml:"autoProvision"`
+	DefaultRoleID string         `yaml:"defaultRoleId"`
+	RoleRules     []OIDCRoleRule `yaml:"roleRules"`
+}
+
+// OIDCRoleRule assigns RoleID when Claim, one of groups, email or email_domain, equals Value.
+// Matching rules also update the role of existing users on every login.
+type OIDCRoleRule struct {
+	Claim  string `yaml:"claim"`
+	Value  string `yaml:"value"`
+	RoleID string `yaml:"roleId"`
+}
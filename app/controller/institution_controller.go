@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/client"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceInstitutionController interface {
+	GetAllInstitution(ctx context.Context) ([]*model.Institution, error)
+	GetInstitutionByID(ctx context.Context, id string) (*model.Institution, error)
+	CreateInstitution(ctx context.Context, request *model.RequestInstitution) (*model.Institution, error)
+	UpdateInstitution(ctx context.Context, request *model.RequestInstitution) error
+	DeleteInstitution(ctx context.Context, id string) error
+}
+
+type InstitutionController struct {
+	institutionClient client.InterfaceInstitutionClient
+	userController    InterfaceUserController
+}
+
+func NewInstitutionController(institutionClient client.InterfaceInstitutionClient, userController InterfaceUserController) *InstitutionController {
+	return &InstitutionController{
+		institutionClient: institutionClient,
+		userController:    userController,
+	}
+}
+
+func (c *InstitutionController) GetAllInstitution(ctx context.Context) ([]*model.Institution, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetAllInstitution")
+	defer span.Finish()
+
+	response, err := c.institutionClient.GetAllInstitution(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *InstitutionController) GetInstitutionByID(ctx context.Context, id string) (*model.Institution, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetInstitutionByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	response, err := c.institutionClient.GetInstitutionByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *InstitutionController) CreateInstitution(ctx context.Context, request *model.RequestInstitution) (*model.Institution, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CreateInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if _, scoped := utils.GetTenant(ctx); scoped {
+		utils.LogEventError(span, errors.New("only a super admin can create institutions"))
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("only a super admin can create institutions"))
+	}
+
+	if err := validateInstitution(request); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	institution := &model.Institution{
+		ID:            uuid.New().String(),
+		Name:          request.Name,
+		Code:          request.Code,
+		Timezone:      request.Timezone,
+		Status:        request.Status,
+		ContactName:   request.ContactName,
+		ContactEmail:  request.ContactEmail,
+		ContactPhone:  request.ContactPhone,
+		StorageBucket: request.StorageBucket,
+		CreatedAt:     time.Now(),
+		CreatedBy:     session.Username,
+		UpdatedAt:     time.Now(),
+		UpdatedBy:     session.Username,
+	}
+
+	if err := c.institutionClient.CreateInstitution(ctx, institution); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", institution)
+
+	return institution, nil
+}
+
+// UpdateInstitution saves an institution. Deactivating it also ends every session of its
+// users, new logins are refused while it stays inactive.
+func (c *InstitutionController) UpdateInstitution(ctx context.Context, request *model.RequestInstitution) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	current, err := c.institutionClient.GetInstitutionByID(ctx, request.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	_, scoped := utils.GetTenant(ctx)
+	if scoped && request.Status == "" {
+		request.Status = current.Status
+	}
+
+	if err := validateInstitution(request); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	// Callers limited to the institution may edit its details, but not switch it off or
+	// repoint it at other data.
+	if scoped && (request.Status != current.Status || request.Code != current.Code || request.StorageBucket != current.StorageBucket) {
+		utils.LogEventError(span, errors.New("only a super admin can change the status, code or storage bucket of an institution"))
+		return model.ThrowError(http.StatusForbidden, errors.New("only a super admin can change the status, code or storage bucket of an institution"))
+	}
+
+	err = c.institutionClient.UpdateInstitution(ctx, &model.Institution{
+		ID:            request.ID,
+		Name:          request.Name,
+		Code:          request.Code,
+		Timezone:      request.Timezone,
+		Status:        request.Status,
+		ContactName:   request.ContactName,
+		ContactEmail:  request.ContactEmail,
+		ContactPhone:  request.ContactPhone,
+		StorageBucket: request.StorageBucket,
+		UpdatedAt:     time.Now(),
+		UpdatedBy:     session.Username,
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if current.Status == model.InstitutionStatusInactive || request.Status != model.InstitutionStatusInactive {
+		return nil
+	}
+
+	usernames, err := c.institutionClient.GetInstitutionUsername(ctx, request.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	for _, username := range usernames {
+		if err := c.userController.ForceLogout(ctx, username); err != nil {
+			utils.LogEventError(span, err)
+			return err
+		}
+	}
+
+	utils.LogEvent(span, "Response", "Success Deactivate Institution")
+
+	return nil
+}
+
+func (c *InstitutionController) DeleteInstitution(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	if _, scoped := utils.GetTenant(ctx); scoped {
+		utils.LogEventError(span, errors.New("only a super admin can delete institutions"))
+		return model.ThrowError(http.StatusForbidden, errors.New("only a super admin can delete institutions"))
+	}
+
+	if err := c.institutionClient.DeleteInstitution(ctx, id); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// validateInstitution fills in the default status and timezone and rejects unknown ones.
+func validateInstitution(request *model.RequestInstitution) error {
+	if request.Status == "" {
+		request.Status = model.InstitutionStatusActive
+	}
+
+	if request.Status != model.InstitutionStatusActive && request.Status != model.InstitutionStatusInactive {
+		return model.ThrowError(http.StatusBadRequest, errors.New("status must be active or inactive"))
+	}
+
+	if request.Timezone == "" {
+		request.Timezone = "UTC"
+	}
+
+	if _, err := time.LoadLocation(request.Timezone); err != nil {
+		return model.ThrowError(http.StatusBadRequest, errors.New("unknown timezone "+request.Timezone))
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	loginFailureKey = "login_failure:%s:%s"
+	loginLockKey    = "login_lock:%s:%s"
+	loginDelayKey   = "login_delay:%s:%s"
+
+	loginScopeUser = "user"
+	loginScopeIP   = "ip"
+
+	loginWindowParam    = "LOGIN_ATTEMPT_WINDOW_MINUTES"
+	loginDelayThreshold = "LOGIN_DELAY_THRESHOLD"
+	loginDelayParam     = "LOGIN_DELAY_SECONDS"
+	loginMaxUserParam   = "LOGIN_MAX_ATTEMPT"
+	loginMaxIPParam     = "LOGIN_MAX_ATTEMPT_IP"
+	loginLockoutParam   = "LOGIN_LOCKOUT_MINUTES"
+
+	maxLoginDelay = 5 * time.Minute
+)
+
+// errInvalidCredential is returned for unknown usernames and wrong passwords alike so that
+// the response does not reveal which usernames exist.
+var errInvalidCredential = model.ThrowError(http.StatusUnauthorized, errors.New("invalid username or password"))
+
+var (
+	dummyPasswordOnce sync.Once
+	dummyPassword     string
+)
+
+// dummyPasswordHash is compared against when the username does not exist. It is made with
+// the configured algorithm so that it costs as much as checking a real password.
+func dummyPasswordHash() string {
+	dummyPasswordOnce.Do(func() {
+		dummyPassword, _ = utils.Passwords.Hash("dummy-password")
+	})
+	return dummyPassword
+}
+
+var errLoginLocked = model.ThrowError(http.StatusTooManyRequests, errors.New("too many failed login attempts, try again later"))
+
+type loginThrottle struct {
+	window    time.Duration
+	threshold int64
+	delay     time.Duration
+	maxUser   int64
+	maxIP     int64
+	lockout   time.Duration
+}
+
+// getParamInt returns a numeric parameter, falling back to def when it is unset or invalid.
+func (c *UserController) getParamInt(ctx context.Context, key string, def int64) int64 {
+	value, err := c.getParamValue(ctx, key)
+	if err != nil || value == "" {
+		return def
+	}
+
+	res, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return def
+	}
+
+	return res
+}
+
+func (c *UserController) getLoginThrottle(ctx context.Context) *loginThrottle {
+	return &loginThrottle{
+		window:    time.Duration(c.getParamInt(ctx, loginWindowParam, 15)) * time.Minute,
+		threshold: c.getParamInt(ctx, loginDelayThreshold, 3),
+		delay:     time.Duration(c.getParamInt(ctx, loginDelayParam, 1)) * time.Second,
+		maxUser:   c.getParamInt(ctx, loginMaxUserParam, 5),
+		maxIP:     c.getParamInt(ctx, loginMaxIPParam, 20),
+		lockout:   time.Duration(c.getParamInt(ctx, loginLockoutParam, 15)) * time.Minute,
+	}
+}
+
+// checkLoginAllowed refuses an attempt while the username or IP address is locked, or
+// while the progressive delay after the last failure has not passed yet.
+func (c *UserController) checkLoginAllowed(ctx context.Context, username string, ip string) error {
+	locked, err := c.redis.Exists(ctx,
+		fmt.Sprintf(loginLockKey, loginScopeUser, username),
+		fmt.Sprintf(loginLockKey, loginScopeIP, ip),
+	).Result()
+	if err != nil {
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if locked > 0 {
+		return errLoginLocked
+	}
+
+	wait, err := c.redis.PTTL(ctx, fmt.Sprintf(loginDelayKey, loginScopeUser, username)).Result()
+	if err != nil {
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if wait > 0 {
+		return model.ThrowError(http.StatusTooManyRequests, fmt.Errorf("too many failed login attempts, retry in %d seconds", int(math.Ceil(wait.Seconds()))))
+	}
+
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt for the username and IP address, delaying
+// further attempts once the threshold is reached and locking them out at the maximum.
+func (c *UserController) recordLoginFailure(ctx context.Context, username string, ip string) error {
+	throttle := c.getLoginThrottle(ctx)
+
+	userFailures, err := c.incrementLoginFailure(ctx, loginScopeUser, username, throttle.window)
+	if err != nil {
+		return err
+	}
+
+	ipFailures, err := c.incrementLoginFailure(ctx, loginScopeIP, ip, throttle.window)
+	if err != nil {
+		return err
+	}
+
+	if throttle.maxUser > 0 && userFailures >= throttle.maxUser {
+		if err := c.redis.Set(ctx, fmt.Sprintf(loginLockKey, loginScopeUser, username), userFailures, throttle.lockout).Err(); err != nil {
+			return err
+		}
+	}
+
+	if throttle.maxIP > 0 && ipFailures >= throttle.maxIP {
+		if err := c.redis.Set(ctx, fmt.Sprintf(loginLockKey, loginScopeIP, ip), ipFailures, throttle.lockout).Err(); err != nil {
+			return err
+		}
+	}
+
+	if throttle.delay > 0 && userFailures >= throttle.threshold {
+		delay := throttle.delay << uint(min(userFailures-throttle.threshold, 16))
+		if delay > maxLoginDelay {
+			delay = maxLoginDelay
+		}
+
+		if err := c.redis.Set(ctx, fmt.Sprintf(loginDelayKey, loginScopeUser, username), 1, delay).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *UserController) incrementLoginFailure(ctx context.Context, scope string, id string, window time.Duration) (int64, error) {
+	key := fmt.Sprintf(loginFailureKey, scope, id)
+
+	count, err := c.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 {
+		if err := c.redis.Expire(ctx, key, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// clearLoginFailure forgets the failed attempts of a username after a successful login or
+// an administrator unlock.
+func (c *UserController) clearLoginFailure(ctx context.Context, scope string, id string) error {
+	return c.redis.Del(ctx,
+		fmt.Sprintf(loginFailureKey, scope, id),
+		fmt.Sprintf(loginLockKey, scope, id),
+		fmt.Sprintf(loginDelayKey, scope, id),
+	).Err()
+}
+
+func (c *UserController) UnlockLogin(ctx context.Context, username string, ip string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UnlockLogin")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]string{"username": username, "ip": ip})
+
+	if _, err := c.userClient.GetUserDetail(ctx, username); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.clearLoginFailure(ctx, loginScopeUser, username); err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if ip != "" {
+		if err := c.clearLoginFailure(ctx, loginScopeIP, ip); err != nil {
+			utils.LogEventError(span, err)
+			return model.ThrowError(http.StatusInternalServerError, err)
+		}
+	}
+
+	utils.LogEvent(span, "Response", "Success Unlock Login")
+
+	return nil
+}
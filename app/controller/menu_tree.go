@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+)
+
+// checkMenuParent refuses a parent menu that does not exist or that would make the menu its
+// own ancestor.
+func (c *RoleController) checkMenuParent(ctx context.Context, menuID string, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+
+	if parentID == menuID {
+		return model.ThrowError(http.StatusBadRequest, errors.New("menu can't be its own parent"))
+	}
+
+	menus, err := c.getCachedMenu(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := map[string]*model.Menu{}
+	for _, menu := range menus {
+		byID[menu.Id] = menu
+	}
+
+	if _, ok := byID[parentID]; !ok {
+		return model.ThrowError(http.StatusBadRequest, errors.New("parent menu not found"))
+	}
+
+	seen := map[string]bool{}
+	for id := parentID; id != "" && !seen[id]; {
+		if id == menuID {
+			return model.ThrowError(http.StatusBadRequest, errors.New("parent menu would create a cycle"))
+		}
+		seen[id] = true
+
+		parent, ok := byID[id]
+		if !ok {
+			break
+		}
+		id = parent.ParentID
+	}
+
+	return nil
+}
+
+// GetMenuTree returns the navigation of the caller: the visible menus any of their roles is
+// mapped to, nested under their parents in sort order. A parent the caller is not mapped to
+// is kept when it holds menus they are, a menu whose parent is gone is shown at the top.
+func (c *RoleController) GetMenuTree(ctx context.Context, claims *model.JwtCustomClaims) ([]*model.MenuTree, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetMenuTree")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", claims.Role)
+
+	response := []*model.MenuTree{}
+
+	roles, err := c.GetUserRoles(ctx, claims)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+			return response, nil
+		}
+		return nil, err
+	}
+
+	if !roles[0].IsActive || (roles[0].MfaRequired && !model.HasMultiFactor(claims.Amr)) {
+		return response, nil
+	}
+
+	mappings, err := c.GetRolesMenuRoleMapping(ctx, roles)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	access := map[string]string{}
+	for _, mapping := range mappings {
+		access[mapping.MenuID] = mapping.AccessMethod
+	}
+
+	menus, err := c.getCachedMenu(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	exists := map[string]bool{}
+	for _, menu := range menus {
+		exists[menu.Id] = true
+	}
+
+	children := map[string][]*model.Menu{}
+	for _, menu := range menus {
+		parentID := menu.ParentID
+		if !exists[parentID] {
+			parentID = ""
+		}
+		children[parentID] = append(children[parentID], menu)
+	}
+
+	seen := map[string]bool{}
+
+	var build func(parentID string) []*model.MenuTree
+	build = func(parentID string) []*model.MenuTree {
+		nodes := []*model.MenuTree{}
+		for _, menu := range children[parentID] {
+			if menu.IsHidden || seen[menu.Id] {
+				continue
+			}
+			seen[menu.Id] = true
+
+			node := &model.MenuTree{
+				Id:           menu.Id,
+				MenuName:     menu.MenuName,
+				MenuRoute:    menu.MenuRoute,
+				Icon:         menu.Icon,
+				SortOrder:    menu.SortOrder,
+				AccessMethod: access[menu.Id],
+				Children:     build(menu.Id),
+			}
+
+			if node.AccessMethod == "" && len(node.Children) == 0 {
+				continue
+			}
+
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	response = build("")
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
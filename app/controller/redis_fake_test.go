@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis answers the commands the login flows send from maps, so the tests need no Redis
+// server. Commands are never sent over the network and expiry is ignored.
+type fakeRedis struct {
+	mu     sync.Mutex
+	data   map[string]string
+	hashes map[string]map[string]string
+}
+
+func newFakeRedis() (*redis.Client, *fakeRedis) {
+	fake := &fakeRedis{
+		data:   map[string]string{},
+		hashes: map[string]map[string]string{},
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	rdb.AddHook(fake)
+
+	return rdb, fake
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		args := make([]string, len(cmd.Args()))
+		for i, arg := range cmd.Args() {
+			if value, ok := arg.([]byte); ok {
+				args[i] = string(value)
+			} else {
+				args[i] = fmt.Sprint(arg)
+			}
+		}
+
+		switch cmd.Name() {
+		case "get", "getdel":
+			value, ok := f.data[args[1]]
+			if !ok {
+				cmd.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			if cmd.Name() == "getdel" {
+				delete(f.data, args[1])
+			}
+			cmd.(*redis.StringCmd).SetVal(value)
+		case "set":
+			f.data[args[1]] = args[2]
+			cmd.(*redis.StatusCmd).SetVal("OK")
+		case "del":
+			var deleted int64
+			for _, key := range args[1:] {
+				if _, ok := f.data[key]; ok {
+					deleted++
+				}
+				if _, ok := f.hashes[key]; ok {
+					deleted++
+				}
+				delete(f.data, key)
+				delete(f.hashes, key)
+			}
+			cmd.(*redis.IntCmd).SetVal(deleted)
+		case "exists":
+			var found int64
+			for _, key := range args[1:] {
+				if _, ok := f.data[key]; ok {
+					found++
+				}
+			}
+			cmd.(*redis.IntCmd).SetVal(found)
+		case "expire":
+			cmd.(*redis.BoolCmd).SetVal(true)
+		case "hget":
+			value, ok := f.hashes[args[1]][args[2]]
+			if !ok {
+				cmd.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			cmd.(*redis.StringCmd).SetVal(value)
+		case "hset":
+			if f.hashes[args[1]] == nil {
+				f.hashes[args[1]] = map[string]string{}
+			}
+			f.hashes[args[1]][args[2]] = args[3]
+			cmd.(*redis.IntCmd).SetVal(1)
+		case "hdel":
+			delete(f.hashes[args[1]], args[2])
+			cmd.(*redis.IntCmd).SetVal(1)
+		case "hkeys":
+			var keys []string
+			for key := range f.hashes[args[1]] {
+				keys = append(keys, key)
+			}
+			cmd.(*redis.StringSliceCmd).SetVal(keys)
+		case "evalsha":
+			// Only rotateRefreshToken is run as a script, its logic is replayed here.
+			if args[1] != rotateRefreshToken.Hash() {
+				err := fmt.Errorf("unexpected redis script %s", args[1])
+				cmd.SetErr(err)
+				return err
+			}
+			key, previous, next := args[3], args[4], args[5]
+			current, ok := f.data[key]
+			switch {
+			case !ok:
+				cmd.(*redis.Cmd).SetVal(int64(0))
+			case current != previous:
+				delete(f.data, key)
+				cmd.(*redis.Cmd).SetVal(int64(-1))
+			default:
+				f.data[key] = next
+				cmd.(*redis.Cmd).SetVal(int64(1))
+			}
+		default:
+			err := fmt.Errorf("unexpected redis command %s", cmd.Name())
+			cmd.SetErr(err)
+			return err
+		}
+
+		return nil
+	}
+}
@@ -2,37 +2,213 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"face-recognition-svc/app/client"
 	"face-recognition-svc/app/model"
 	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	menuCacheKey           = "permission:menu"
+	rolePermissionCacheKey = "permission:role:%s"
+	roleDetailCacheKey     = "permission:role_detail:%s"
 )
 
 type InterfaceRoleController interface {
+	GetMenuRoleMapping(ctx context.Context, roleID string) ([]*model.MenuRoleMapping, error)
+	GetEffectiveMenuRoleMapping(ctx context.Context, roleID string) ([]*model.MenuRoleMapping, error)
+	GetEffectivePermission(ctx context.Context, roleID string) ([]*model.EffectivePermission, error)
+	GetUserRoles(ctx context.Context, claims *model.JwtCustomClaims) ([]*model.Role, error)
+	GetRolesMenuRoleMapping(ctx context.Context, roles []*model.Role) ([]*model.MenuRoleMapping, error)
 	CreateNewRoleMapping(ctx context.Context, request *model.MenuRoleMapping) error
 	GetAllRoleMapping(ctx context.Context) ([]*model.MenuRoleMapping, error)
+	UpdateRoleMapping(ctx context.Context, request *model.MenuRoleMapping) error
+	DeleteRoleMapping(ctx context.Context, id string) error
+	SetRolePermission(ctx context.Context, request *model.RequestRolePermission) (*model.ResponsePermissionDiff, error)
 
 	GetAllMenu(ctx context.Context) ([]*model.Menu, error)
 	CreateNewMenu(ctx context.Context, request *model.Menu) error
 	UpdateMenu(ctx context.Context, request *model.Menu) error
 	DeleteMenu(ctx context.Context, id string) error
+	GetMenuTree(ctx context.Context, claims *model.JwtCustomClaims) ([]*model.MenuTree, error)
 
 	GetAllRole(ctx context.Context) ([]*model.Role, error)
 	CreateNewRole(ctx context.Context, request *model.Role) error
+	UpdateRole(ctx context.Context, request *model.Role) error
+	UpdateRoleStatus(ctx context.Context, request *model.Role) error
+	DeleteRole(ctx context.Context, id string) error
+	GetRoleByID(ctx context.Context, id string) (*model.Role, error)
+
+	GetUserRole(ctx context.Context, username string) ([]*model.UserRole, error)
+	GrantUserRole(ctx context.Context, request *model.UserRole) error
+	RevokeUserRole(ctx context.Context, username string, id string) error
+
+	Authorize(ctx context.Context, claims *model.JwtCustomClaims, route string, method string) error
+	IsCrossTenant(ctx context.Context, claims *model.JwtCustomClaims) (bool, error)
+	CheckAssignableRole(ctx context.Context, roleID string) error
 }
 
 type RoleController struct {
-	roleClient client.InterfaceRoleClient
+	redis          *redis.Client
+	roleClient     client.InterfaceRoleClient
+	userRoleClient client.InterfaceUserRoleClient
 }
 
-func NewRoleController(roleClient client.InterfaceRoleClient) *RoleController {
+func NewRoleController(redis *redis.Client, roleClient client.InterfaceRoleClient, userRoleClient client.InterfaceUserRoleClient) *RoleController {
 	return &RoleController{
-		roleClient: roleClient,
+		redis:          redis,
+		roleClient:     roleClient,
+		userRoleClient: userRoleClient,
 	}
 }
 
+// GetMenuRoleMapping returns the menus mapped to a role from the permission cache,
+// loading it from the database on a miss.
+func (c *RoleController) GetMenuRoleMapping(ctx context.Context, roleID string) ([]*model.MenuRoleMapping, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetMenuRoleMapping")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", roleID)
+
+	key := fmt.Sprintf(rolePermissionCacheKey, roleID)
+
+	var response []*model.MenuRoleMapping
+
+	cache := c.redis.Get(ctx, key).Val()
+	if cache != "" {
+		if err := json.Unmarshal([]byte(cache), &response); err != nil {
+			utils.LogEventError(span, err)
+		} else {
+			return response, nil
+		}
+	}
+
+	response, err := c.roleClient.GetMenuRoleMapping(ctx, roleID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	resJSON, err := json.Marshal(response)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return response, nil
+	}
+
+	if err := c.redis.Set(ctx, key, resJSON, 6*time.Hour).Err(); err != nil {
+		utils.LogEventError(span, err)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+// GetRoleByID returns a role from the permission cache, loading it from the database on a miss.
+func (c *RoleController) GetRoleByID(ctx context.Context, id string) (*model.Role, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRoleByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	key := fmt.Sprintf(roleDetailCacheKey, id)
+
+	cache := c.redis.Get(ctx, key).Val()
+	if cache != "" {
+		resCache := &model.Role{}
+		if err := json.Unmarshal([]byte(cache), resCache); err != nil {
+			utils.LogEventError(span, err)
+		} else {
+			return resCache, nil
+		}
+	}
+
+	response, err := c.roleClient.GetRoleByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	resJSON, err := json.Marshal(response)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return response, nil
+	}
+
+	if err := c.redis.Set(ctx, key, resJSON, 6*time.Hour).Err(); err != nil {
+		utils.LogEventError(span, err)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+// getCachedMenu returns every menu from the permission cache, loading it from the
+// database on a miss.
+func (c *RoleController) getCachedMenu(ctx context.Context) ([]*model.Menu, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: getCachedMenu")
+	defer span.Finish()
+
+	var response []*model.Menu
+
+	cache := c.redis.Get(ctx, menuCacheKey).Val()
+	if cache != "" {
+		if err := json.Unmarshal([]byte(cache), &response); err != nil {
+			utils.LogEventError(span, err)
+		} else {
+			return response, nil
+		}
+	}
+
+	response, err := c.roleClient.GetAllMenu(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	resJSON, err := json.Marshal(response)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return response, nil
+	}
+
+	if err := c.redis.Set(ctx, menuCacheKey, resJSON, 6*time.Hour).Err(); err != nil {
+		utils.LogEventError(span, err)
+	}
+
+	return response, nil
+}
+
+// invalidateRolePermission drops the cached role and permissions of a single role.
+func (c *RoleController) invalidateRolePermission(ctx context.Context, roleID string) error {
+	return c.redis.Del(ctx, fmt.Sprintf(rolePermissionCacheKey, roleID), fmt.Sprintf(roleDetailCacheKey, roleID)).Err()
+}
+
+// invalidateAllPermission drops the cached menus and the cached permissions of every role,
+// since each cached mapping row carries the menu route it grants.
+func (c *RoleController) invalidateAllPermission(ctx context.Context) error {
+	keys := []string{menuCacheKey}
+
+	iter := c.redis.Scan(ctx, 0, fmt.Sprintf(rolePermissionCacheKey, "*"), 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	return c.redis.Del(ctx, keys...).Err()
+}
+
 func (c *RoleController) CreateNewRoleMapping(ctx context.Context, request *model.MenuRoleMapping) error {
 	span, ctx := utils.SpanFromContext(ctx, "Controller: CreateNewRoleMapping")
 	defer span.Finish()
@@ -55,6 +231,12 @@ func (c *RoleController) CreateNewRoleMapping(ctx context.Context, request *mode
 		utils.LogEventError(span, err)
 		return err
 	}
+
+	if err := c.invalidateRolePermission(ctx, request.RoleID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	return nil
 }
 
@@ -99,6 +281,12 @@ func (c *RoleController) CreateNewMenu(ctx context.Context, request *model.Menu)
 	}
 
 	request.Id = uuid.New().String()
+
+	if err := c.checkMenuParent(ctx, request.Id, request.ParentID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	request.CreatedAt = time.Now()
 	request.UpdatedAt = time.Now()
 	request.CreatedBy = session.Username
@@ -111,6 +299,12 @@ func (c *RoleController) CreateNewMenu(ctx context.Context, request *model.Menu)
 		utils.LogEventError(span, err)
 		return err
 	}
+
+	if err := c.invalidateAllPermission(ctx); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	return nil
 }
 
@@ -139,7 +333,19 @@ func (c *RoleController) CreateNewRole(ctx context.Context, request *model.Role)
 		return err
 	}
 
+	if err := c.checkSuperAdminRole(ctx, request, nil); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	request.Id = uuid.New().String()
+
+	if err := c.checkRoleParent(ctx, request.Id, request.ParentID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	request.IsActive = true
 	request.CreatedAt = time.Now()
 	request.UpdatedAt = time.Now()
 	request.CreatedBy = session.Username
@@ -152,6 +358,201 @@ func (c *RoleController) CreateNewRole(ctx context.Context, request *model.Role)
 		utils.LogEventError(span, err)
 		return err
 	}
+
+	if err := c.invalidateRolePermission(ctx, request.Id); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *RoleController) UpdateRole(ctx context.Context, request *model.Role) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateRole")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	current, err := c.GetRoleByID(ctx, request.Id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.checkSuperAdminRole(ctx, request, current); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.checkRoleParent(ctx, request.Id, request.ParentID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	request.UpdatedAt = time.Now()
+	request.UpdatedBy = session.Username
+
+	utils.LogEvent(span, "Request", request)
+
+	err = c.roleClient.UpdateRole(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.invalidateRolePermission(ctx, request.Id); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Role")
+
+	return nil
+}
+
+func (c *RoleController) UpdateRoleStatus(ctx context.Context, request *model.Role) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateRoleStatus")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	request.UpdatedAt = time.Now()
+	request.UpdatedBy = session.Username
+
+	utils.LogEvent(span, "Request", request)
+
+	err = c.roleClient.UpdateRoleStatus(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.invalidateRolePermission(ctx, request.Id); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Role Status")
+
+	return nil
+}
+
+func (c *RoleController) DeleteRole(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	count, err := c.roleClient.CountUserByRole(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if count > 0 {
+		err := fmt.Errorf("role is still assigned to %d user(s)", count)
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusConflict, err)
+	}
+
+	children, err := c.roleClient.CountChildRole(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if children > 0 {
+		err := fmt.Errorf("role is still the parent of %d role(s)", children)
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusConflict, err)
+	}
+
+	err = c.roleClient.DeleteRole(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.invalidateRolePermission(ctx, id); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Role")
+
+	return nil
+}
+
+func (c *RoleController) UpdateRoleMapping(ctx context.Context, request *model.MenuRoleMapping) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateRoleMapping")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	mapping, err := c.roleClient.GetRoleMappingByID(ctx, request.Id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	request.UpdatedAt = time.Now()
+	request.UpdatedBy = session.Username
+
+	utils.LogEvent(span, "Request", request)
+
+	err = c.roleClient.UpdateRoleMapping(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.invalidateRolePermission(ctx, mapping.RoleID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Role Mapping")
+
+	return nil
+}
+
+func (c *RoleController) DeleteRoleMapping(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteRoleMapping")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	mapping, err := c.roleClient.GetRoleMappingByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err = c.roleClient.DeleteRoleMapping(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.invalidateRolePermission(ctx, mapping.RoleID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Role Mapping")
+
 	return nil
 }
 
@@ -165,6 +566,11 @@ func (c *RoleController) UpdateMenu(ctx context.Context, request *model.Menu) er
 		return err
 	}
 
+	if err := c.checkMenuParent(ctx, request.Id, request.ParentID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	request.UpdatedAt = time.Now()
 	request.UpdatedBy = session.Username
 
@@ -176,6 +582,11 @@ func (c *RoleController) UpdateMenu(ctx context.Context, request *model.Menu) er
 		return err
 	}
 
+	if err := c.invalidateAllPermission(ctx); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	utils.LogEvent(span, "Response", "Success Update Menu")
 
 	return nil
@@ -187,13 +598,163 @@ func (c *RoleController) DeleteMenu(ctx context.Context, id string) error {
 
 	utils.LogEvent(span, "Request", id)
 
-	err := c.roleClient.DeleteMenu(ctx, id)
+	count, err := c.roleClient.CountChildMenu(ctx, id)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return err
 	}
 
+	if count > 0 {
+		err := fmt.Errorf("menu is still the parent of %d menu(s)", count)
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusConflict, err)
+	}
+
+	err = c.roleClient.DeleteMenu(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.invalidateAllPermission(ctx); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	utils.LogEvent(span, "Response", "Success Delete Menu")
 
 	return nil
 }
+
+// CheckAssignableRole refuses unknown roles, and super admin roles for callers limited to
+// one institution, when a role is assigned to a user or a service account.
+func (c *RoleController) CheckAssignableRole(ctx context.Context, roleID string) error {
+	role, err := c.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+
+	if _, scoped := utils.GetTenant(ctx); scoped && role.IsSuperAdmin {
+		return model.ThrowError(http.StatusForbidden, errors.New("only a super admin can assign super admin roles"))
+	}
+
+	return nil
+}
+
+// checkSuperAdminRole keeps callers limited to one institution from creating super admin
+// roles or changing existing ones, which would let them escape their institution.
+func (c *RoleController) checkSuperAdminRole(ctx context.Context, request *model.Role, current *model.Role) error {
+	if _, scoped := utils.GetTenant(ctx); !scoped {
+		return nil
+	}
+
+	if request.IsSuperAdmin || (current != nil && current.IsSuperAdmin) {
+		return model.ThrowError(http.StatusForbidden, errors.New("only a super admin can manage super admin roles"))
+	}
+
+	return nil
+}
+
+// IsCrossTenant reports whether one of the caller's roles may act across institutions. Unknown
+// roles are limited to their institution, Authorize refuses them anyway.
+func (c *RoleController) IsCrossTenant(ctx context.Context, claims *model.JwtCustomClaims) (bool, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: IsCrossTenant")
+	defer span.Finish()
+
+	roles, err := c.GetUserRoles(ctx, claims)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, role := range roles {
+		if role.IsSuperAdmin && role.IsActive {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Authorize resolves the menu that owns route, the one with the longest matching
+// menu_route, and checks that one of the caller's roles, the primary role or an active
+// grant, is mapped to it with the given method directly or through an ancestor role.
+// Routes not owned by any menu are denied, as are all routes for roles that require
+// two-factor authentication when the token was issued without it.
+func (c *RoleController) Authorize(ctx context.Context, claims *model.JwtCustomClaims, route string, method string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: Authorize")
+	defer span.Finish()
+
+	roleID := claims.Role
+
+	utils.LogEvent(span, "Request", map[string]string{"role_id": roleID, "route": route, "method": method})
+
+	roles, err := c.GetUserRoles(ctx, claims)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+			return model.ThrowError(http.StatusForbidden, errors.New("Anda Tidak Memiliki Akses"))
+		}
+		return err
+	}
+
+	role := roles[0]
+
+	if !role.IsActive {
+		utils.LogEventError(span, errors.New("role is inactive"))
+		return model.ThrowError(http.StatusForbidden, errors.New("role is inactive"))
+	}
+
+	if role.MfaRequired && !model.HasMultiFactor(claims.Amr) {
+		utils.LogEventError(span, errors.New("two-factor authentication is required for this role"))
+		return model.ThrowError(http.StatusForbidden, errors.New("two-factor authentication is required for this role"))
+	}
+
+	menus, err := c.getCachedMenu(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	var owner *model.Menu
+	for _, menu := range menus {
+		if !utils.MatchRoute(route, menu.MenuRoute) {
+			continue
+		}
+		if owner == nil || len(strings.TrimSuffix(menu.MenuRoute, "/")) > len(strings.TrimSuffix(owner.MenuRoute, "/")) {
+			owner = menu
+		}
+	}
+
+	if owner == nil {
+		utils.LogEventError(span, errors.New("route is not registered to any menu"))
+		return model.ThrowError(http.StatusForbidden, errors.New("Anda Tidak Memiliki Akses"))
+	}
+
+	mappings, err := c.GetRolesMenuRoleMapping(ctx, roles)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	for _, mapping := range mappings {
+		if mapping.MenuID != owner.Id {
+			continue
+		}
+
+		for _, access := range strings.Split(mapping.AccessMethod, ",") {
+			if strings.EqualFold(strings.TrimSpace(access), method) {
+				return nil
+			}
+		}
+
+		utils.LogEventError(span, errors.New("method not mapped for menu "+owner.Id))
+		return model.ThrowError(http.StatusForbidden, errors.New("Method Not Allowed"))
+	}
+
+	utils.LogEventError(span, errors.New("role is not mapped to menu "+owner.Id))
+	return model.ThrowError(http.StatusForbidden, errors.New("Anda Tidak Memiliki Akses"))
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"strings"
+)
+
+// getRoleAncestry returns the role followed by its parent, grandparent and so on. The walk
+// stops at a missing parent or at a role already visited, so a cycle left behind in the
+// database cannot loop forever.
+func (c *RoleController) getRoleAncestry(ctx context.Context, roleID string) ([]*model.Role, error) {
+	var ancestry []*model.Role
+	seen := map[string]bool{}
+
+	for id := roleID; id != "" && !seen[id]; {
+		seen[id] = true
+
+		role, err := c.GetRoleByID(ctx, id)
+		if err != nil {
+			if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound && len(ancestry) > 0 {
+				break
+			}
+			return nil, err
+		}
+
+		ancestry = append(ancestry, role)
+		id = role.ParentID
+	}
+
+	return ancestry, nil
+}
+
+// checkRoleParent refuses a parent that does not exist or that would make the role its own
+// ancestor.
+func (c *RoleController) checkRoleParent(ctx context.Context, roleID string, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+
+	if parentID == roleID {
+		return model.ThrowError(http.StatusBadRequest, errors.New("role can't be its own parent"))
+	}
+
+	ancestry, err := c.getRoleAncestry(ctx, parentID)
+	if err != nil {
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+			return model.ThrowError(http.StatusBadRequest, errors.New("parent role not found"))
+		}
+		return err
+	}
+
+	for _, ancestor := range ancestry {
+		if ancestor.Id == roleID {
+			return model.ThrowError(http.StatusBadRequest, errors.New("parent role would create a cycle"))
+		}
+	}
+
+	return nil
+}
+
+// GetEffectivePermission returns, per menu, the union of the methods mapped to the role and
+// to its active ancestors, together with the mappings each permission comes from.
+func (c *RoleController) GetEffectivePermission(ctx context.Context, roleID string) ([]*model.EffectivePermission, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetEffectivePermission")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", roleID)
+
+	ancestry, err := c.getRoleAncestry(ctx, roleID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var response []*model.EffectivePermission
+	byMenu := map[string]*model.EffectivePermission{}
+
+	for i, role := range ancestry {
+		if i > 0 && !role.IsActive {
+			continue
+		}
+
+		mappings, err := c.GetMenuRoleMapping(ctx, role.Id)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		for _, mapping := range mappings {
+			permission, ok := byMenu[mapping.MenuID]
+			if !ok {
+				permission = &model.EffectivePermission{
+					MenuID:    mapping.MenuID,
+					MenuName:  mapping.MenuName,
+					MenuRoute: mapping.MenuRoute,
+				}
+				byMenu[mapping.MenuID] = permission
+				response = append(response, permission)
+			}
+
+			permission.AccessMethod = mergeAccessMethod(permission.AccessMethod, mapping.AccessMethod)
+			permission.Sources = append(permission.Sources, &model.PermissionSource{
+				MappingID:    mapping.Id,
+				RoleID:       role.Id,
+				RoleName:     role.RoleName,
+				AccessMethod: mapping.AccessMethod,
+				Inherited:    i > 0,
+			})
+		}
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+// GetEffectiveMenuRoleMapping returns the role's effective permissions shaped as menu
+// mappings, one row per menu, for callers that only need the merged methods.
+func (c *RoleController) GetEffectiveMenuRoleMapping(ctx context.Context, roleID string) ([]*model.MenuRoleMapping, error) {
+	role, err := c.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := c.GetEffectivePermission(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []*model.MenuRoleMapping
+	for _, permission := range permissions {
+		response = append(response, &model.MenuRoleMapping{
+			MenuID:       permission.MenuID,
+			RoleID:       roleID,
+			RoleName:     role.RoleName,
+			MenuName:     permission.MenuName,
+			MenuRoute:    permission.MenuRoute,
+			AccessMethod: permission.AccessMethod,
+		})
+	}
+
+	return response, nil
+}
+
+// mergeAccessMethod adds the comma separated methods of extra to current, keeping the first
+// spelling of each method and ignoring case when looking for duplicates.
+func mergeAccessMethod(current string, extra string) string {
+	var methods []string
+	seen := map[string]bool{}
+
+	for _, list := range []string{current, extra} {
+		for _, method := range strings.Split(list, ",") {
+			method = strings.TrimSpace(method)
+			if method == "" || seen[strings.ToUpper(method)] {
+				continue
+			}
+			seen[strings.ToUpper(method)] = true
+			methods = append(methods, method)
+		}
+	}
+
+	return strings.Join(methods, ",")
+}
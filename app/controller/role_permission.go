@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"context"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// accessMethods are the methods a menu mapping may grant.
+var accessMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// normalizeAccessMethod upper-cases and dedupes a comma separated method list, keeping the
+// given order, and refuses methods a mapping can't grant.
+func normalizeAccessMethod(value string) (string, error) {
+	var methods []string
+	seen := map[string]bool{}
+
+	for _, method := range strings.Split(value, ",") {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" || seen[method] {
+			continue
+		}
+		if !accessMethods[method] {
+			return "", fmt.Errorf("unsupported access method %q", method)
+		}
+		seen[method] = true
+		methods = append(methods, method)
+	}
+
+	return strings.Join(methods, ","), nil
+}
+
+// sameAccessMethod reports whether two method lists grant the same methods.
+func sameAccessMethod(a string, b string) bool {
+	set := func(value string) map[string]bool {
+		methods := map[string]bool{}
+		for _, method := range strings.Split(value, ",") {
+			if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+				methods[method] = true
+			}
+		}
+		return methods
+	}
+
+	setA, setB := set(a), set(b)
+	if len(setA) != len(setB) {
+		return false
+	}
+	for method := range setA {
+		if !setB[method] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SetRolePermission replaces the menu mappings of a role with the given matrix. The current
+// mappings are diffed against it and the inserts, updates and deletes are applied in one
+// transaction, or only reported with DryRun. Permissions inherited from parent roles are not
+// part of the matrix.
+func (c *RoleController) SetRolePermission(ctx context.Context, request *model.RequestRolePermission) (*model.ResponsePermissionDiff, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: SetRolePermission")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	role, err := c.GetRoleByID(ctx, request.RoleID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := c.checkSuperAdminRole(ctx, role, nil); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	menus, err := c.getCachedMenu(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	menuName := map[string]string{}
+	for _, menu := range menus {
+		menuName[menu.Id] = menu.MenuName
+	}
+
+	desired := map[string]string{}
+	var order []string
+
+	for _, permission := range request.Permissions {
+		if _, ok := menuName[permission.MenuID]; !ok {
+			err := fmt.Errorf("menu %s not found", permission.MenuID)
+			utils.LogEventError(span, err)
+			return nil, model.ThrowError(http.StatusBadRequest, err)
+		}
+
+		if _, ok := desired[permission.MenuID]; ok {
+			err := fmt.Errorf("menu %s is listed more than once", permission.MenuID)
+			utils.LogEventError(span, err)
+			return nil, model.ThrowError(http.StatusBadRequest, err)
+		}
+
+		access, err := normalizeAccessMethod(permission.AccessMethod)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, model.ThrowError(http.StatusBadRequest, err)
+		}
+
+		desired[permission.MenuID] = access
+		order = append(order, permission.MenuID)
+	}
+
+	current, err := c.roleClient.GetMenuRoleMapping(ctx, request.RoleID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	now := time.Now()
+
+	response := &model.ResponsePermissionDiff{
+		RoleID:  request.RoleID,
+		DryRun:  request.DryRun,
+		Created: []*model.MenuRoleMapping{},
+		Updated: []*model.PermissionChange{},
+		Deleted: []*model.MenuRoleMapping{},
+	}
+
+	var updated []*model.MenuRoleMapping
+	var deleted []string
+	kept := map[string]bool{}
+
+	for _, mapping := range current {
+		access, ok := desired[mapping.MenuID]
+		if !ok || access == "" || kept[mapping.MenuID] {
+			response.Deleted = append(response.Deleted, mapping)
+			deleted = append(deleted, mapping.Id)
+			continue
+		}
+		kept[mapping.MenuID] = true
+
+		if sameAccessMethod(mapping.AccessMethod, access) {
+			response.Unchanged++
+			continue
+		}
+
+		response.Updated = append(response.Updated, &model.PermissionChange{
+			Id:         mapping.Id,
+			MenuID:     mapping.MenuID,
+			MenuName:   mapping.MenuName,
+			FromMethod: mapping.AccessMethod,
+			ToMethod:   access,
+		})
+		updated = append(updated, &model.MenuRoleMapping{
+			Id:           mapping.Id,
+			AccessMethod: access,
+			UpdatedAt:    now,
+			UpdatedBy:    session.Username,
+		})
+	}
+
+	for _, menuID := range order {
+		if kept[menuID] || desired[menuID] == "" {
+			continue
+		}
+
+		response.Created = append(response.Created, &model.MenuRoleMapping{
+			MenuID:       menuID,
+			RoleID:       request.RoleID,
+			RoleName:     role.RoleName,
+			MenuName:     menuName[menuID],
+			AccessMethod: desired[menuID],
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			CreatedBy:    session.Username,
+			UpdatedBy:    session.Username,
+		})
+	}
+
+	if request.DryRun || len(response.Created)+len(updated)+len(deleted) == 0 {
+		utils.LogEvent(span, "Response", response)
+		return response, nil
+	}
+
+	if err := c.roleClient.ApplyRoleMapping(ctx, response.Created, updated, deleted); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := c.invalidateRolePermission(ctx, request.RoleID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
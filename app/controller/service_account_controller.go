@@ -0,0 +1,387 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/app/client"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	apiKeyCacheKey    = "api_key:%s"
+	apiKeyUsedKey     = "api_key_used:%s"
+	apiKeyPrefix      = "frk_"
+	apiKeyCacheExpiry = time.Minute
+
+	// apiKeyUsedInterval limits how often last_used_at is written for a busy key.
+	apiKeyUsedInterval = time.Minute
+
+	serviceAccountUsernamePrefix = "sa:"
+)
+
+var errInvalidApiKey = model.ThrowError(http.StatusUnauthorized, errors.New("invalid api key"))
+
+type InterfaceServiceAccountController interface {
+	GetAllServiceAccount(ctx context.Context) ([]*model.ServiceAccount, error)
+	CreateServiceAccount(ctx context.Context, request *model.RequestServiceAccount) (*model.ServiceAccount, error)
+	UpdateServiceAccount(ctx context.Context, request *model.RequestServiceAccount) error
+	DeleteServiceAccount(ctx context.Context, id string) error
+
+	GetApiKeyByServiceAccount(ctx context.Context, serviceAccountID string) ([]*model.ApiKey, error)
+	CreateApiKey(ctx context.Context, request *model.RequestCreateApiKey) (*model.ResponseCreateApiKey, error)
+	RevokeApiKey(ctx context.Context, id string) error
+
+	AuthenticateApiKey(ctx context.Context, key string) (*model.JwtCustomClaims, error)
+}
+
+type ServiceAccountController struct {
+	redis                *redis.Client
+	serviceAccountClient client.InterfaceServiceAccountClient
+	roleController       InterfaceRoleController
+}
+
+func NewServiceAccountController(redis *redis.Client, serviceAccountClient client.InterfaceServiceAccountClient, roleController InterfaceRoleController) *ServiceAccountController {
+	return &ServiceAccountController{
+		redis:                redis,
+		serviceAccountClient: serviceAccountClient,
+		roleController:       roleController,
+	}
+}
+
+func (c *ServiceAccountController) GetAllServiceAccount(ctx context.Context) ([]*model.ServiceAccount, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetAllServiceAccount")
+	defer span.Finish()
+
+	response, err := c.serviceAccountClient.GetAllServiceAccount(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *ServiceAccountController) CreateServiceAccount(ctx context.Context, request *model.RequestServiceAccount) (*model.ServiceAccount, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CreateServiceAccount")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := c.roleController.CheckAssignableRole(ctx, request.RoleID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if tenant, scoped := utils.GetTenant(ctx); scoped {
+		request.InstitutionID = tenant
+	}
+
+	account := &model.ServiceAccount{
+		ID:            uuid.New().String(),
+		Name:          request.Name,
+		Description:   request.Description,
+		RoleID:        request.RoleID,
+		InstitutionID: request.InstitutionID,
+		IsActive:      true,
+		CreatedAt:     time.Now(),
+		CreatedBy:     session.Username,
+		UpdatedAt:     time.Now(),
+		UpdatedBy:     session.Username,
+	}
+
+	err = c.serviceAccountClient.CreateServiceAccount(ctx, account)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", account)
+
+	return account, nil
+}
+
+func (c *ServiceAccountController) UpdateServiceAccount(ctx context.Context, request *model.RequestServiceAccount) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateServiceAccount")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.roleController.CheckAssignableRole(ctx, request.RoleID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if tenant, scoped := utils.GetTenant(ctx); scoped {
+		request.InstitutionID = tenant
+	}
+
+	err = c.serviceAccountClient.UpdateServiceAccount(ctx, &model.ServiceAccount{
+		ID:            request.ID,
+		Name:          request.Name,
+		Description:   request.Description,
+		RoleID:        request.RoleID,
+		InstitutionID: request.InstitutionID,
+		IsActive:      request.IsActive,
+		UpdatedAt:     time.Now(),
+		UpdatedBy:     session.Username,
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.invalidateApiKeyCache(ctx, request.ID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *ServiceAccountController) DeleteServiceAccount(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteServiceAccount")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	// Evict the cached keys first, they can no longer be listed once deleted.
+	if err := c.invalidateApiKeyCache(ctx, id); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err := c.serviceAccountClient.DeleteServiceAccount(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// invalidateApiKeyCache evicts every cached key of a service account, so changes to the
+// account apply to its keys right away.
+func (c *ServiceAccountController) invalidateApiKeyCache(ctx context.Context, serviceAccountID string) error {
+	keys, err := c.serviceAccountClient.GetApiKeyByServiceAccount(ctx, serviceAccountID)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	cacheKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		cacheKeys = append(cacheKeys, fmt.Sprintf(apiKeyCacheKey, key.KeyHash))
+	}
+
+	if err := c.redis.Del(ctx, cacheKeys...).Err(); err != nil {
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+func (c *ServiceAccountController) GetApiKeyByServiceAccount(ctx context.Context, serviceAccountID string) ([]*model.ApiKey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetApiKeyByServiceAccount")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", serviceAccountID)
+
+	if _, err := c.serviceAccountClient.GetServiceAccountByID(ctx, serviceAccountID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	response, err := c.serviceAccountClient.GetApiKeyByServiceAccount(ctx, serviceAccountID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *ServiceAccountController) CreateApiKey(ctx context.Context, request *model.RequestCreateApiKey) (*model.ResponseCreateApiKey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CreateApiKey")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if _, err := c.serviceAccountClient.GetServiceAccountByID(ctx, request.ServiceAccountID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	key := apiKeyPrefix + token
+
+	apiKey := &model.ApiKey{
+		ID:               uuid.New().String(),
+		ServiceAccountID: request.ServiceAccountID,
+		KeyPrefix:        key[:len(apiKeyPrefix)+8],
+		KeyHash:          utils.HashToken(key),
+		CreatedAt:        time.Now(),
+		CreatedBy:        session.Username,
+	}
+
+	if request.ExpiryDays > 0 {
+		expiredAt := time.Now().AddDate(0, 0, request.ExpiryDays)
+		apiKey.ExpiredAt = &expiredAt
+	}
+
+	err = c.serviceAccountClient.CreateApiKey(ctx, apiKey)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", apiKey)
+
+	return &model.ResponseCreateApiKey{
+		ApiKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+func (c *ServiceAccountController) RevokeApiKey(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RevokeApiKey")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	apiKey, err := c.serviceAccountClient.GetApiKeyByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err = c.serviceAccountClient.RevokeApiKey(ctx, id, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.redis.Del(ctx, fmt.Sprintf(apiKeyCacheKey, apiKey.KeyHash)).Err(); err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// AuthenticateApiKey resolves an API key to the claims its service account acts with.
+// Lookups are cached briefly; revocation and account changes evict the cache.
+func (c *ServiceAccountController) AuthenticateApiKey(ctx context.Context, key string) (*model.JwtCustomClaims, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: AuthenticateApiKey")
+	defer span.Finish()
+
+	keyHash := utils.HashToken(key)
+	cacheKey := fmt.Sprintf(apiKeyCacheKey, keyHash)
+
+	var identity *model.ApiKeyIdentity
+
+	cache := c.redis.Get(ctx, cacheKey).Val()
+	if cache != "" {
+		identity = &model.ApiKeyIdentity{}
+		if err := json.Unmarshal([]byte(cache), identity); err != nil {
+			utils.LogEventError(span, err)
+			identity = nil
+		}
+	}
+
+	if identity == nil {
+		var err error
+		identity, err = c.serviceAccountClient.GetApiKeyIdentity(ctx, keyHash)
+		if err != nil {
+			utils.LogEventError(span, err)
+			if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+				return nil, errInvalidApiKey
+			}
+			return nil, err
+		}
+
+		if resJSON, err := json.Marshal(identity); err == nil {
+			if err := c.redis.Set(ctx, cacheKey, resJSON, apiKeyCacheExpiry).Err(); err != nil {
+				utils.LogEventError(span, err)
+			}
+		}
+	}
+
+	utils.LogEvent(span, "Request", identity.ID)
+
+	now := time.Now()
+	if identity.RevokedAt != nil || !identity.IsActive || (identity.ExpiredAt != nil && now.After(*identity.ExpiredAt)) {
+		utils.LogEventError(span, errors.New("api key is revoked, expired or inactive"))
+		return nil, errInvalidApiKey
+	}
+
+	fresh, err := c.redis.SetNX(ctx, fmt.Sprintf(apiKeyUsedKey, identity.ID), 1, apiKeyUsedInterval).Result()
+	if err != nil {
+		utils.LogEventError(span, err)
+	} else if fresh {
+		if err := c.serviceAccountClient.UpdateApiKeyLastUsed(ctx, identity.ID, now); err != nil {
+			utils.LogEventError(span, err)
+		}
+	}
+
+	claims := &model.JwtCustomClaims{
+		Name:        serviceAccountUsernamePrefix + identity.Name,
+		Role:        identity.RoleID,
+		Institution: identity.InstitutionID,
+		Amr:         []string{model.AuthMethodApiKey},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:      identity.ID,
+			Subject: identity.ServiceAccountID,
+		},
+	}
+
+	if identity.ExpiredAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*identity.ExpiredAt)
+	}
+
+	return claims, nil
+}
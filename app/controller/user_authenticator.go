@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+var errAuthenticatorUnavailable = model.ThrowError(http.StatusServiceUnavailable, errors.New("authentication service is unavailable, try again later"))
+
+// authenticate checks a username and password. Local users are checked against their
+// password hash, which is upgraded when it was made with weaker settings than configured,
+// and users of an external auth_source against that authenticator. Unknown usernames
+// are offered to the external authenticators for provisioning.
+func (c *UserController) authenticate(ctx context.Context, username string, password string) (*model.UserDetail, error) {
+	user, err := c.userClient.GetUserCredential(ctx, username)
+	if err != nil {
+		if data, ok := err.(*model.ErrorResponse); !ok || data.Code != http.StatusNotFound {
+			return nil, err
+		}
+		return c.provisionExternalUser(ctx, username, password)
+	}
+
+	if user.AuthSource == "" || user.AuthSource == model.AuthSourceLocal {
+		ok, rehash := utils.Passwords.Verify(user.Password, password)
+		if !ok {
+			return nil, errInvalidCredential
+		}
+		if rehash {
+			c.rehashPassword(ctx, user, password)
+		}
+		return &user.UserDetail, nil
+	}
+
+	authenticator, ok := c.authenticators[user.AuthSource]
+	if !ok {
+		return nil, errInvalidCredential
+	}
+
+	external, err := authenticator.Authenticate(ctx, username, password)
+	if err != nil {
+		return nil, externalAuthError(err)
+	}
+
+	if external.RoleID != "" && external.RoleID != user.RoleID {
+		if err := c.userClient.UpdateUserRole(ctx, user.Username, external.RoleID, model.SystemActor); err != nil {
+			return nil, err
+		}
+
+		// Tokens issued before carry the previous role.
+		if err := c.revokeAllSessions(ctx, user.Username, ""); err != nil {
+			return nil, model.ThrowError(http.StatusInternalServerError, err)
+		}
+
+		user.RoleID = external.RoleID
+	}
+
+	return &user.UserDetail, nil
+}
+
+// provisionExternalUser creates a user on the first successful login against an external
+// authenticator that allows it.
+func (c *UserController) provisionExternalUser(ctx context.Context, username string, password string) (*model.UserDetail, error) {
+	sources := make([]string, 0, len(c.authenticators))
+	for source := range c.authenticators {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		external, err := c.authenticators[source].Authenticate(ctx, username, password)
+		if err != nil {
+			if err = externalAuthError(err); err == errInvalidCredential {
+				continue
+			}
+			return nil, err
+		}
+
+		roleID := external.RoleID
+		if roleID == "" {
+			roleID = external.DefaultRoleID
+		}
+
+		if !external.AutoProvision || roleID == "" {
+			return nil, errInvalidCredential
+		}
+
+		fullname := external.Fullname
+		if fullname == "" {
+			fullname = username
+		}
+
+		user := &model.User{
+			UserDetail: model.UserDetail{
+				Username:      username,
+				Email:         external.Email,
+				Fullname:      fullname,
+				Shortname:     username,
+				RoleID:        roleID,
+				InstitutionID: external.InstitutionID,
+				IsActive:      true,
+				AuthSource:    source,
+			},
+			Password: model.UnusablePassword,
+		}
+
+		if err := c.userClient.CreateNewUser(ctx, user); err != nil {
+			return nil, err
+		}
+
+		return &user.UserDetail, nil
+	}
+
+	// Spend the same hashing work as for a known user so timing does not reveal it either
+	utils.Passwords.Verify(dummyPasswordHash(), password)
+
+	return nil, errInvalidCredential
+}
+
+// checkLocalPassword refuses password changes for users whose password is kept elsewhere.
+func checkLocalPassword(user *model.UserDetail) error {
+	if user.AuthSource != "" && user.AuthSource != model.AuthSourceLocal {
+		return model.ThrowError(http.StatusBadRequest, fmt.Errorf("password is managed by %s", user.AuthSource))
+	}
+	return nil
+}
+
+// externalAuthError hides whether an external account exists behind errInvalidCredential.
+func externalAuthError(err error) error {
+	if data, ok := err.(*model.ErrorResponse); ok && (data.Code == http.StatusUnauthorized || data.Code == http.StatusNotFound) {
+		return errInvalidCredential
+	}
+	return errAuthenticatorUnavailable
+}
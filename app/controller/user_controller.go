@@ -2,50 +2,187 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"face-recognition-svc/app/client"
 	"face-recognition-svc/app/model"
 	"face-recognition-svc/app/utils"
+	"fmt"
 	"net/http"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
+const (
+	refreshTokenKey = "refresh_token:%s"
+	userSessionsKey = "user_sessions:%s"
+
+	registrationModeParam        = "REGISTRATION_MODE"
+	registrationRoleParam        = "REGISTRATION_DEFAULT_ROLE"
+	registrationInstitutionParam = "REGISTRATION_DEFAULT_INSTITUTION"
+	registrationModeOpen         = "open"
+
+	defaultInvitationExpiry = 72 * time.Hour
+)
+
+// rotateRefreshToken swaps the current refresh token id of a session for a new one.
+// It returns 1 on success, 0 when the session no longer exists and -1 when the
+// presented token is not the current one, in which case the session is revoked.
+var rotateRefreshToken = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	return -1
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
 type InterfaceUserController interface {
-	CreateNewUser(ctx context.Context, request *model.User) error
-	GetUserDetail(ctx context.Context, username string) (*model.User, error)
+	CreateNewUser(ctx context.Context, request *model.RequestRegister) error
+	GetUserDetail(ctx context.Context, username string) (*model.UserDetail, error)
 	Login(ctx context.Context, request *model.RequestLogin) (*model.ResponseLogin, error)
-	GetAllUser(ctx context.Context) ([]*model.User, error)
+	LoginMfa(ctx context.Context, request *model.RequestLoginMfa) (*model.ResponseLogin, error)
+	StartOIDCLogin(ctx context.Context, institutionID string) (*model.ResponseOIDCLogin, error)
+	OIDCCallback(ctx context.Context, request *model.RequestOIDCCallback) (*model.ResponseLogin, error)
+	BeginPasskeyLogin(ctx context.Context) (*model.ResponsePasskeyLogin, error)
+	LoginPasskey(ctx context.Context, request *model.RequestLoginPasskey) (*model.ResponseLogin, error)
+	GetLoginHistory(ctx context.Context, request *model.RequestLoginHistory) (*model.ResponsePagination, error)
+	GetUserSession(ctx context.Context, username string) ([]*model.UserSession, error)
+	RefreshToken(ctx context.Context, request *model.RequestRefreshToken) (*model.ResponseLogin, error)
+	Logout(ctx context.Context) error
+	LogoutAll(ctx context.Context) error
+	ForceLogout(ctx context.Context, username string) error
+	UpdateUser(ctx context.Context, request *model.RequestUpdateUser) error
+	UpdateUserStatus(ctx context.Context, request *model.RequestUserStatus) error
+	DeleteUser(ctx context.Context, username string) error
+	ResetPassword(ctx context.Context, username string) (*model.ResponseResetPassword, error)
+
+	UnlockLogin(ctx context.Context, username string, ip string) error
+	ResetUserMfa(ctx context.Context, username string) error
+
+	GetProfile(ctx context.Context) (*model.UserDetail, error)
+	GetMyMenu(ctx context.Context) ([]*model.MenuTree, error)
+	UpdateProfile(ctx context.Context, request *model.RequestUpdateProfile) error
+	ChangePassword(ctx context.Context, request *model.RequestChangePassword) error
+	EnrollMfa(ctx context.Context) (*model.ResponseMfaEnroll, error)
+	ActivateMfa(ctx context.Context, request *model.RequestMfaCode) (*model.ResponseMfaRecoveryCode, error)
+	RegenerateRecoveryCode(ctx context.Context, request *model.RequestMfaCode) (*model.ResponseMfaRecoveryCode, error)
+	DisableMfa(ctx context.Context, request *model.RequestMfaCode) error
+	GetPasskey(ctx context.Context) ([]*model.Passkey, error)
+	BeginPasskeyRegistration(ctx context.Context) (*protocol.CredentialCreation, error)
+	FinishPasskeyRegistration(ctx context.Context, request *model.RequestPasskeyRegister) (*model.Passkey, error)
+	DeletePasskey(ctx context.Context, id string) error
+	GetMySession(ctx context.Context) ([]*model.UserSession, error)
+	RevokeMySession(ctx context.Context, sessionID string) error
+	GetMyLoginHistory(ctx context.Context, request *model.RequestLoginHistory) (*model.ResponsePagination, error)
+
+	Impersonate(ctx context.Context, request *model.RequestImpersonation) (*model.ResponseImpersonation, error)
+	AuditImpersonation(ctx context.Context, audit *model.ImpersonationAudit)
+	GetImpersonationAudit(ctx context.Context, request *model.RequestImpersonationAudit) (*model.ResponsePagination, error)
+
+	CreateInvitation(ctx context.Context, request *model.RequestCreateInvitation) (*model.ResponseCreateInvitation, error)
+	GetAllInvitation(ctx context.Context) ([]*model.Invitation, error)
+	DeleteInvitation(ctx context.Context, id string) error
+	AcceptInvitation(ctx context.Context, request *model.RequestAcceptInvitation) error
+	GetAllUser(ctx context.Context) ([]*model.UserDetail, error)
 	GetInstitutionList(ctx context.Context) ([]string, error)
 }
 
 type UserController struct {
-	userClient client.InterfaceUserClient
-	roleClient client.InterfaceRoleClient
+	redis               *redis.Client
+	userClient          client.InterfaceUserClient
+	invitationClient    client.InterfaceInvitationClient
+	mfaClient           client.InterfaceMfaClient
+	oidcClient          client.InterfaceOIDCClient
+	passkeyClient       client.InterfacePasskeyClient
+	loginHistoryClient  client.InterfaceLoginHistoryClient
+	impersonationClient client.InterfaceImpersonationClient
+	institutionClient   client.InterfaceInstitutionClient
+	authenticators      map[string]client.InterfaceAuthenticator
+	roleController      InterfaceRoleController
+	paramController     InterfaceParamController
 }
 
-func NewUserController(userClient client.InterfaceUserClient, roleClient client.InterfaceRoleClient) *UserController {
+func NewUserController(redis *redis.Client, userClient client.InterfaceUserClient, invitationClient client.InterfaceInvitationClient, mfaClient client.InterfaceMfaClient, oidcClient client.InterfaceOIDCClient, passkeyClient client.InterfacePasskeyClient, loginHistoryClient client.InterfaceLoginHistoryClient, impersonationClient client.InterfaceImpersonationClient, institutionClient client.InterfaceInstitutionClient, authenticators map[string]client.InterfaceAuthenticator, roleController InterfaceRoleController, paramController InterfaceParamController) *UserController {
 	return &UserController{
-		userClient: userClient,
-		roleClient: roleClient,
+		redis:               redis,
+		userClient:          userClient,
+		invitationClient:    invitationClient,
+		mfaClient:           mfaClient,
+		oidcClient:          oidcClient,
+		passkeyClient:       passkeyClient,
+		loginHistoryClient:  loginHistoryClient,
+		impersonationClient: impersonationClient,
+		institutionClient:   institutionClient,
+		authenticators:      authenticators,
+		roleController:      roleController,
+		paramController:     paramController,
 	}
 }
 
-func (c *UserController) CreateNewUser(ctx context.Context, request *model.User) error {
+// CreateNewUser handles open self-registration. It is refused unless the registration mode
+// parameter is open, and always assigns the default role and institution from the
+// parameter table rather than anything chosen by the caller.
+func (c *UserController) CreateNewUser(ctx context.Context, request *model.RequestRegister) error {
 	span, ctx := utils.SpanFromContext(ctx, "Controller: CreateNewUser")
 	defer span.Finish()
 
-	utils.LogEvent(span, "Request", request)
+	utils.LogEvent(span, "Request", request.Username)
+
+	mode, err := c.getParamValue(ctx, registrationModeParam)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if mode != registrationModeOpen {
+		utils.LogEventError(span, errors.New("self registration is disabled"))
+		return model.ThrowError(http.StatusForbidden, errors.New("self registration is disabled, ask an administrator for an invitation"))
+	}
+
+	roleID, err := c.getParamValue(ctx, registrationRoleParam)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if roleID == "" {
+		utils.LogEventError(span, errors.New("default registration role is not configured"))
+		return model.ThrowError(http.StatusInternalServerError, errors.New("default registration role is not configured"))
+	}
 
-	hashPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	institutionID, err := c.getParamValue(ctx, registrationInstitutionParam)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return err
 	}
 
-	request.Password = string(hashPassword)
+	hashPassword, err := c.hashPassword(ctx, request.Username, request.Password, nil)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
 
-	err = c.userClient.CreateNewUser(ctx, request)
+	user := &model.User{
+		UserDetail: model.UserDetail{
+			Username:      request.Username,
+			Email:         request.Email,
+			Fullname:      request.Fullname,
+			Shortname:     request.Shortname,
+			RoleID:        roleID,
+			InstitutionID: institutionID,
+		},
+		Password: hashPassword,
+	}
+
+	err = c.userClient.CreateNewUser(ctx, user)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return err
@@ -53,7 +190,7 @@ func (c *UserController) CreateNewUser(ctx context.Context, request *model.User)
 	return nil
 }
 
-func (c *UserController) GetUserDetail(ctx context.Context, username string) (*model.User, error) {
+func (c *UserController) GetUserDetail(ctx context.Context, username string) (*model.UserDetail, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Controller: GetUserDetail")
 	defer span.Finish()
 
@@ -68,59 +205,372 @@ func (c *UserController) GetUserDetail(ctx context.Context, username string) (*m
 	return user, nil
 }
 
-func (c *UserController) Login(ctx context.Context, request *model.RequestLogin) (*model.ResponseLogin, error) {
+func (c *UserController) Login(ctx context.Context, request *model.RequestLogin) (response *model.ResponseLogin, err error) {
 	span, ctx := utils.SpanFromContext(ctx, "Controller: Login")
 	defer span.Finish()
 
-	utils.LogEvent(span, "Request", request)
+	utils.LogEvent(span, "Request", request.Username)
 
-	user, err := c.userClient.GetUserDetail(ctx, request.Username)
+	history := &model.LoginHistory{
+		Username:  request.Username,
+		Method:    model.AuthMethodPassword,
+		IPAddress: request.IPAddress,
+		UserAgent: request.UserAgent,
+	}
+	defer func() { c.recordLogin(ctx, history, err) }()
+
+	if err := c.checkLoginAllowed(ctx, request.Username, request.IPAddress); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	user, err := c.authenticate(ctx, request.Username, request.Password)
+	if err != nil {
+		if err == errInvalidCredential {
+			if err := c.recordLoginFailure(ctx, request.Username, request.IPAddress); err != nil {
+				utils.LogEventError(span, err)
+			}
+		}
+
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	mfa, err := c.mfaClient.GetUserMfa(ctx, user.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if mfa != nil && mfa.IsEnabled {
+		response, err := c.createMfaChallenge(ctx, user.Username)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		history.Outcome = model.LoginOutcomeMfaRequired
+
+		return response, nil
+	}
+
+	if err := c.clearLoginFailure(ctx, loginScopeUser, request.Username); err != nil {
+		utils.LogEventError(span, err)
+	}
+
+	history.SessionID = uuid.New().String()
+
+	response, err = c.issueSession(ctx, user, history.SessionID, "", []string{model.AuthMethodPassword})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *UserController) RefreshToken(ctx context.Context, request *model.RequestRefreshToken) (*model.ResponseLogin, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RefreshToken")
+	defer span.Finish()
+
+	claims, err := c.userClient.ParseRefreshToken(ctx, request.RefreshToken)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", claims.SessionID)
+
+	user, err := c.userClient.GetUserDetail(ctx, claims.Name)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	response, err := c.issueSession(ctx, user, claims.SessionID, claims.ID, claims.Amr)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// issueSession mints an access token and a refresh token for the session, carrying the
+// authentication methods in amr, and returns them with the role's current menu mappings.
+// When previousTokenID is set the refresh token is rotated and presenting any refresh token
+// other than the latest one revokes the whole session.
+func (c *UserController) issueSession(ctx context.Context, user *model.UserDetail, sessionID string, previousTokenID string, amr []string) (*model.ResponseLogin, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: issueSession")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", sessionID)
+
+	if !user.IsActive {
+		utils.LogEventError(span, errors.New("user is deactivated"))
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("user is deactivated"))
+	}
+
+	if err := c.checkInstitutionActive(ctx, user.InstitutionID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	userRole, err := c.roleController.GetRoleByID(ctx, user.RoleID)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
-		utils.LogEventError(span, errors.New("invalid username or password "))
-		return nil, model.ThrowError(http.StatusBadRequest, errors.New("invalid username or password "))
+	if !userRole.IsActive {
+		utils.LogEventError(span, errors.New("role is inactive"))
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("role is inactive"))
+	}
+
+	roles, err := c.roleController.GetUserRoles(ctx, &model.JwtCustomClaims{Name: user.Username, Role: user.RoleID, Amr: amr})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
 	}
 
-	role, err := c.roleClient.GetMenuRoleMapping(ctx, user.RoleID)
+	role, err := c.roleController.GetRolesMenuRoleMapping(ctx, roles)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return nil, err
 	}
 
+	var roleIDs []string
+	for _, granted := range roles {
+		roleIDs = append(roleIDs, granted.Id)
+	}
+
 	if len(role) < 1 {
 		utils.LogEventError(span, errors.New("menu role mapping not found"))
 		return nil, model.ThrowError(http.StatusBadRequest, errors.New("menu role mapping not found"))
 	}
 
-	menuMapping := make(map[string]string)
-	for _, v := range role {
-		menuMapping[v.MenuID] = v.AccessMethod
+	accessToken, accessExpired, err := c.userClient.CreateAccessToken(ctx, user, sessionID, amr)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
 	}
 
-	accessToken, _, err := c.userClient.CreateAccessToken(ctx, user, false, menuMapping)
+	tokenID := uuid.New().String()
+	refreshToken, refreshExpired, err := c.userClient.CreateRefreshToken(ctx, user, sessionID, tokenID, amr)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return nil, model.ThrowError(http.StatusInternalServerError, err)
 	}
 
+	key := fmt.Sprintf(refreshTokenKey, sessionID)
+	ttl := time.Until(time.Unix(refreshExpired, 0))
+
+	if previousTokenID == "" {
+		if err := c.redis.Set(ctx, key, tokenID, ttl).Err(); err != nil {
+			utils.LogEventError(span, err)
+			return nil, model.ThrowError(http.StatusInternalServerError, err)
+		}
+	} else {
+		rotated, err := rotateRefreshToken.Run(ctx, c.redis, []string{key}, previousTokenID, tokenID, ttl.Milliseconds()).Int()
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, model.ThrowError(http.StatusInternalServerError, err)
+		}
+
+		switch rotated {
+		case 0:
+			utils.LogEventError(span, errors.New("refresh token has been revoked"))
+			return nil, model.ThrowError(http.StatusUnauthorized, errors.New("refresh token has been revoked"))
+		case -1:
+			// The script only dropped the refresh token, access tokens already issued for the
+			// session must stop working too.
+			if err := c.revokeSession(ctx, user.Username, sessionID); err != nil {
+				utils.LogEventError(span, err)
+				return nil, model.ThrowError(http.StatusInternalServerError, err)
+			}
+			utils.LogEventError(span, errors.New("refresh token reuse detected, session revoked"))
+			return nil, model.ThrowError(http.StatusUnauthorized, errors.New("refresh token reuse detected, session revoked"))
+		}
+	}
+
+	if err := c.trackSession(ctx, user.Username, sessionID, time.Unix(accessExpired, 0), ttl); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
 	response := &model.ResponseLogin{
 		Username:      user.Username,
 		Fullname:      user.Fullname,
 		Shortname:     user.Shortname,
 		Role:          user.RoleID,
+		Roles:         roleIDs,
 		Token:         accessToken,
+		RefreshToken:  refreshToken,
 		InstitutionID: user.InstitutionID,
 		MenuMapping:   role,
+
+		MfaEnrollmentRequired: userRole.MfaRequired && !model.HasMultiFactor(amr),
 	}
 
 	return response, nil
 }
 
-func (c *UserController) GetAllUser(ctx context.Context) ([]*model.User, error) {
+// trackSession records the latest access token expiry of a session so that revoking the
+// session can keep it on the denylist for exactly as long as its tokens stay valid.
+func (c *UserController) trackSession(ctx context.Context, username string, sessionID string, accessExpiredAt time.Time, ttl time.Duration) error {
+	key := fmt.Sprintf(userSessionsKey, username)
+
+	session := &model.UserSession{
+		SessionID: sessionID,
+		Username:  username,
+		CreatedAt: time.Now(),
+	}
+
+	cache := c.redis.HGet(ctx, key, sessionID).Val()
+	if cache != "" {
+		if err := json.Unmarshal([]byte(cache), session); err != nil {
+			return err
+		}
+	}
+
+	session.AccessExpiredAt = accessExpiredAt
+
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	if err := c.redis.HSet(ctx, key, sessionID, sessionJSON).Err(); err != nil {
+		return err
+	}
+
+	return c.redis.Expire(ctx, key, ttl).Err()
+}
+
+// revokeSession ends a login session: its refresh token stops working and all access
+// tokens issued for it are rejected by the auth middleware.
+func (c *UserController) revokeSession(ctx context.Context, username string, sessionID string) error {
+	key := fmt.Sprintf(userSessionsKey, username)
+
+	cache := c.redis.HGet(ctx, key, sessionID).Val()
+	if cache != "" {
+		session := &model.UserSession{}
+		if err := json.Unmarshal([]byte(cache), session); err != nil {
+			return err
+		}
+
+		if err := utils.RevokeSession(ctx, c.redis, sessionID, time.Until(session.AccessExpiredAt)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.redis.Del(ctx, fmt.Sprintf(refreshTokenKey, sessionID)).Err(); err != nil {
+		return err
+	}
+
+	return c.redis.HDel(ctx, key, sessionID).Err()
+}
+
+// revokeAllSessions ends every session of a user except the one given, if any.
+func (c *UserController) revokeAllSessions(ctx context.Context, username string, exceptSessionID string) error {
+	sessions, err := c.redis.HKeys(ctx, fmt.Sprintf(userSessionsKey, username)).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessions {
+		if sessionID == exceptSessionID {
+			continue
+		}
+
+		if err := c.revokeSession(ctx, username, sessionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *UserController) Logout(ctx context.Context) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: Logout")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Request", session)
+
+	err = utils.RevokeToken(ctx, c.redis, session.TokenID, time.Until(time.Unix(session.ExpiredAt, 0)))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if session.SessionID != "" {
+		if err := c.revokeSession(ctx, session.Username, session.SessionID); err != nil {
+			utils.LogEventError(span, err)
+			return model.ThrowError(http.StatusInternalServerError, err)
+		}
+	}
+
+	utils.LogEvent(span, "Response", "Success Logout")
+
+	return nil
+}
+
+func (c *UserController) LogoutAll(ctx context.Context) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: LogoutAll")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Request", session)
+
+	err = utils.RevokeToken(ctx, c.redis, session.TokenID, time.Until(time.Unix(session.ExpiredAt, 0)))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if err := c.revokeAllSessions(ctx, session.Username, ""); err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Logout All Sessions")
+
+	return nil
+}
+
+func (c *UserController) ForceLogout(ctx context.Context, username string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ForceLogout")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	if _, err := c.userClient.GetUserDetail(ctx, username); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.revokeAllSessions(ctx, username, ""); err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Force Logout")
+
+	return nil
+}
+
+func (c *UserController) GetAllUser(ctx context.Context) ([]*model.UserDetail, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Controller: GetAllUser")
 	defer span.Finish()
 
@@ -149,3 +599,521 @@ func (c *UserController) GetInstitutionList(ctx context.Context) ([]string, erro
 
 	return institutionList, nil
 }
+
+func (c *UserController) UpdateUser(ctx context.Context, request *model.RequestUpdateUser) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateUser")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	request.UpdatedAt = time.Now()
+	request.UpdatedBy = session.Username
+
+	utils.LogEvent(span, "Request", request)
+
+	user, err := c.userClient.GetUserDetail(ctx, request.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.roleController.CheckAssignableRole(ctx, request.RoleID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.checkInstitutionExists(ctx, request.InstitutionID); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err = c.userClient.UpdateUser(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	// Tokens carry the role and institution, so changing either only takes effect on a new session
+	if user.RoleID != request.RoleID || user.InstitutionID != request.InstitutionID {
+		if err := c.revokeAllSessions(ctx, request.Username, ""); err != nil {
+			utils.LogEventError(span, err)
+			return model.ThrowError(http.StatusInternalServerError, err)
+		}
+	}
+
+	utils.LogEvent(span, "Response", "Success Update User")
+
+	return nil
+}
+
+func (c *UserController) UpdateUserStatus(ctx context.Context, request *model.RequestUserStatus) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateUserStatus")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if request.Username == session.Username && !request.IsActive {
+		utils.LogEventError(span, errors.New("cannot deactivate your own account"))
+		return model.ThrowError(http.StatusBadRequest, errors.New("cannot deactivate your own account"))
+	}
+
+	request.UpdatedAt = time.Now()
+	request.UpdatedBy = session.Username
+
+	utils.LogEvent(span, "Request", request)
+
+	err = c.userClient.UpdateUserStatus(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if !request.IsActive {
+		if err := c.revokeAllSessions(ctx, request.Username, ""); err != nil {
+			utils.LogEventError(span, err)
+			return model.ThrowError(http.StatusInternalServerError, err)
+		}
+	}
+
+	utils.LogEvent(span, "Response", "Success Update User Status")
+
+	return nil
+}
+
+func (c *UserController) DeleteUser(ctx context.Context, username string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteUser")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if username == session.Username {
+		utils.LogEventError(span, errors.New("cannot delete your own account"))
+		return model.ThrowError(http.StatusBadRequest, errors.New("cannot delete your own account"))
+	}
+
+	err = c.userClient.DeleteUser(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.revokeAllSessions(ctx, username, ""); err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete User")
+
+	return nil
+}
+
+// ResetPassword replaces the password of a user with a generated temporary one and ends
+// all of their sessions. The temporary password is only returned once.
+func (c *UserController) ResetPassword(ctx context.Context, username string) (*model.ResponseResetPassword, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ResetPassword")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	user, err := c.userClient.GetUserDetail(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := checkLocalPassword(user); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	length := int(c.getParamInt(ctx, passwordMinLengthParam, 10))
+	if length < temporaryPasswordLength {
+		length = temporaryPasswordLength
+	}
+
+	var password, hashPassword string
+
+	// A generated password only fails the policy when it happens to contain the username.
+	for attempt := 0; attempt < 5 && hashPassword == ""; attempt++ {
+		password, err = utils.GeneratePassword(length)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, model.ThrowError(http.StatusInternalServerError, err)
+		}
+
+		hashPassword, err = c.hashPassword(ctx, username, password, nil)
+		if err != nil {
+			if data, ok := err.(*model.ErrorResponse); !ok || data.Code != http.StatusBadRequest {
+				utils.LogEventError(span, err)
+				return nil, err
+			}
+		}
+	}
+
+	if hashPassword == "" {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, errors.New("could not generate a password matching the password policy"))
+	}
+
+	err = c.userClient.UpdatePassword(ctx, username, hashPassword)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := c.revokeAllSessions(ctx, username, ""); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Reset Password")
+
+	return &model.ResponseResetPassword{
+		Username:          username,
+		TemporaryPassword: password,
+	}, nil
+}
+
+func (c *UserController) GetProfile(ctx context.Context) (*model.UserDetail, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetProfile")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	user, err := c.userClient.GetUserDetail(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", user)
+
+	return user, nil
+}
+
+// GetMyMenu returns the navigation menu tree of the caller's roles.
+func (c *UserController) GetMyMenu(ctx context.Context) ([]*model.MenuTree, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetMyMenu")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	claims := &model.JwtCustomClaims{Name: session.Username, Role: session.RoleID, Amr: session.Amr}
+
+	response, err := c.roleController.GetMenuTree(ctx, claims)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *UserController) UpdateProfile(ctx context.Context, request *model.RequestUpdateProfile) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateProfile")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	request.UpdatedAt = time.Now()
+	request.UpdatedBy = session.Username
+
+	utils.LogEvent(span, "Request", request)
+
+	err = c.userClient.UpdateProfile(ctx, session.Username, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Profile")
+
+	return nil
+}
+
+// ChangePassword verifies the current password before storing the new one and ends every
+// other session of the user, keeping only the one that made the change.
+func (c *UserController) ChangePassword(ctx context.Context, request *model.RequestChangePassword) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ChangePassword")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	user, err := c.userClient.GetUserCredential(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := checkLocalPassword(&user.UserDetail); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if ok, _ := utils.Passwords.Verify(user.Password, request.CurrentPassword); !ok {
+		utils.LogEventError(span, errors.New("current password is incorrect"))
+		return model.ThrowError(http.StatusBadRequest, errors.New("current password is incorrect"))
+	}
+
+	previous, err := c.getPreviousPassword(ctx, session.Username, user.Password)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	hashPassword, err := c.hashPassword(ctx, session.Username, request.NewPassword, previous)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err = c.userClient.UpdatePassword(ctx, session.Username, hashPassword)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.revokeAllSessions(ctx, session.Username, session.SessionID); err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Change Password")
+
+	return nil
+}
+
+// checkInstitutionExists refuses institution ids that are not registered, or that are not
+// the caller's own for callers limited to one institution. Users without an institution
+// are allowed, except by such callers.
+func (c *UserController) checkInstitutionExists(ctx context.Context, institutionID string) error {
+	if tenant, scoped := utils.GetTenant(ctx); scoped && institutionID != tenant {
+		return model.ThrowError(http.StatusBadRequest, errors.New("institution not found"))
+	}
+
+	if institutionID == "" {
+		return nil
+	}
+
+	if _, err := c.institutionClient.GetInstitutionByID(ctx, institutionID); err != nil {
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+			return model.ThrowError(http.StatusBadRequest, errors.New("institution not found"))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkInstitutionActive refuses sessions for users of a deactivated institution.
+func (c *UserController) checkInstitutionActive(ctx context.Context, institutionID string) error {
+	if institutionID == "" {
+		return nil
+	}
+
+	institution, err := c.institutionClient.GetInstitutionByID(ctx, institutionID)
+	if err != nil {
+		return err
+	}
+
+	if institution.Status != model.InstitutionStatusActive {
+		return model.ThrowError(http.StatusForbidden, errors.New("institution is deactivated"))
+	}
+
+	return nil
+}
+
+// getParamValue returns the value of a parameter, or an empty string when it is not set.
+func (c *UserController) getParamValue(ctx context.Context, key string) (string, error) {
+	param, err := c.paramController.GetParameterByKey(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	if param == nil {
+		return "", nil
+	}
+
+	return param.Value, nil
+}
+
+// CreateInvitation stores an invitation and returns its one-time token. Only the hash
+// of the token is kept, so it cannot be retrieved again later.
+func (c *UserController) CreateInvitation(ctx context.Context, request *model.RequestCreateInvitation) (*model.ResponseCreateInvitation, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CreateInvitation")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := c.roleController.CheckAssignableRole(ctx, request.RoleID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := c.checkInstitutionExists(ctx, request.InstitutionID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	expiry := defaultInvitationExpiry
+	if request.ExpiryHours > 0 {
+		expiry = time.Duration(request.ExpiryHours) * time.Hour
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	invitation := &model.Invitation{
+		ID:            uuid.New().String(),
+		TokenHash:     utils.HashToken(token),
+		Email:         request.Email,
+		RoleID:        request.RoleID,
+		InstitutionID: request.InstitutionID,
+		ExpiredAt:     time.Now().Add(expiry),
+		CreatedAt:     time.Now(),
+		CreatedBy:     session.Username,
+	}
+
+	err = c.invitationClient.CreateInvitation(ctx, invitation)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", invitation)
+
+	return &model.ResponseCreateInvitation{
+		Invitation: invitation,
+		Token:      token,
+	}, nil
+}
+
+func (c *UserController) GetAllInvitation(ctx context.Context) ([]*model.Invitation, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetAllInvitation")
+	defer span.Finish()
+
+	invitations, err := c.invitationClient.GetAllInvitation(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", invitations)
+
+	return invitations, nil
+}
+
+func (c *UserController) DeleteInvitation(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteInvitation")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	err := c.invitationClient.DeleteInvitation(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Invitation")
+
+	return nil
+}
+
+// AcceptInvitation creates the invited account with the email, role and institution
+// chosen by the administrator; the invitee only picks a username, names and password.
+func (c *UserController) AcceptInvitation(ctx context.Context, request *model.RequestAcceptInvitation) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: AcceptInvitation")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request.Username)
+
+	invitation, err := c.invitationClient.GetInvitationByToken(ctx, utils.HashToken(request.Token))
+	if err != nil {
+		utils.LogEventError(span, err)
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+			return model.ThrowError(http.StatusBadRequest, errors.New("invalid invitation token"))
+		}
+		return err
+	}
+
+	if invitation.UsedAt != nil || time.Now().After(invitation.ExpiredAt) {
+		utils.LogEventError(span, errors.New("invitation has expired or was already used"))
+		return model.ThrowError(http.StatusGone, errors.New("invitation has expired or was already used"))
+	}
+
+	hashPassword, err := c.hashPassword(ctx, request.Username, request.Password, nil)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	user := &model.User{
+		UserDetail: model.UserDetail{
+			Username:      request.Username,
+			Email:         invitation.Email,
+			Fullname:      request.Fullname,
+			Shortname:     request.Shortname,
+			RoleID:        invitation.RoleID,
+			InstitutionID: invitation.InstitutionID,
+		},
+		Password: hashPassword,
+	}
+
+	err = c.invitationClient.AcceptInvitation(ctx, invitation, user)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Accept Invitation")
+
+	return nil
+}
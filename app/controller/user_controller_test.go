@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// sessionUserClient issues opaque tokens that carry their session and token id in the
+// clear, so refreshing works without signing keys.
+type sessionUserClient struct {
+	*fakeUserClient
+}
+
+func (f *sessionUserClient) CreateAccessToken(ctx context.Context, user *model.UserDetail, sessionID string, amr []string) (string, int64, error) {
+	return "access:" + sessionID, time.Now().Add(time.Hour).Unix(), nil
+}
+
+func (f *sessionUserClient) CreateRefreshToken(ctx context.Context, user *model.UserDetail, sessionID string, tokenID string, amr []string) (string, int64, error) {
+	return strings.Join([]string{"refresh", user.Username, sessionID, tokenID}, ":"), time.Now().Add(24 * time.Hour).Unix(), nil
+}
+
+func (f *sessionUserClient) ParseRefreshToken(ctx context.Context, token string) (*model.JwtRefreshClaims, error) {
+	parts := strings.Split(token, ":")
+	return &model.JwtRefreshClaims{
+		Name:             parts[1],
+		SessionID:        parts[2],
+		RegisteredClaims: jwt.RegisteredClaims{ID: parts[3]},
+	}, nil
+}
+
+// fakeRoleController knows a single active role mapped to one menu.
+type fakeRoleController struct {
+	InterfaceRoleController
+}
+
+func (f *fakeRoleController) GetRoleByID(ctx context.Context, id string) (*model.Role, error) {
+	return &model.Role{Id: id, IsActive: true}, nil
+}
+
+func (f *fakeRoleController) GetUserRoles(ctx context.Context, claims *model.JwtCustomClaims) ([]*model.Role, error) {
+	return []*model.Role{{Id: claims.Role, IsActive: true}}, nil
+}
+
+func (f *fakeRoleController) GetRolesMenuRoleMapping(ctx context.Context, roles []*model.Role) ([]*model.MenuRoleMapping, error) {
+	return []*model.MenuRoleMapping{{MenuID: "menu-1", RoleID: roles[0].Id, AccessMethod: http.MethodGet}}, nil
+}
+
+// accessTokenStatus runs an access token of the session through IsTokenActive and returns
+// the response status.
+func accessTokenStatus(rdb *redis.Client, sessionID string) int {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/service/me", nil), rec)
+	c.Set("user", &jwt.Token{Valid: true, Claims: &model.JwtCustomClaims{
+		Name:             "jane",
+		SessionID:        sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{ID: "access-token-1"},
+	}})
+
+	handler := utils.IsTokenActive(rdb)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	if err := handler(c); err != nil {
+		return http.StatusInternalServerError
+	}
+
+	return rec.Code
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+
+	rdb, _ := newFakeRedis()
+	users := &sessionUserClient{newFakeUserClient(&model.UserDetail{Username: "jane", RoleID: "viewer", IsActive: true})}
+	c := &UserController{redis: rdb, userClient: users, roleController: &fakeRoleController{}}
+
+	login, err := c.issueSession(ctx, users.users["jane"], "session-1", "", []string{"pwd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.RefreshToken(ctx, &model.RequestRefreshToken{RefreshToken: login.RefreshToken}); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := accessTokenStatus(rdb, "session-1"); status != http.StatusOK {
+		t.Fatalf("access token before reuse: status = %d, want 200", status)
+	}
+
+	// Someone replays the refresh token that was already rotated.
+	_, err = c.RefreshToken(ctx, &model.RequestRefreshToken{RefreshToken: login.RefreshToken})
+	if data, ok := err.(*model.ErrorResponse); !ok || data.Code != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token: err = %v, want 401", err)
+	}
+
+	if status := accessTokenStatus(rdb, "session-1"); status != http.StatusUnauthorized {
+		t.Errorf("access token after reuse: status = %d, want 401", status)
+	}
+}
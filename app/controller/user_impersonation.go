@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	impersonationMinutesParam  = "IMPERSONATION_MINUTES"
+	impersonationReadOnlyParam = "IMPERSONATION_READ_ONLY"
+
+	// impersonationRoute is the menu route whose POST permission allows impersonating.
+	impersonationRoute = "/impersonation"
+)
+
+// Impersonate issues the caller a short-lived access token for another user. The token
+// names the caller in its "act" claim, cannot be refreshed and expires with the caller's
+// own token at the latest. Users who may impersonate cannot be impersonated themselves.
+func (c *UserController) Impersonate(ctx context.Context, request *model.RequestImpersonation) (*model.ResponseImpersonation, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: Impersonate")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", map[string]string{"actor": session.Username, "username": request.Username})
+
+	if session.Actor != "" {
+		utils.LogEventError(span, errors.New("already impersonating"))
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("not allowed while impersonating"))
+	}
+
+	if request.Username == session.Username {
+		utils.LogEventError(span, errors.New("cannot impersonate yourself"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("cannot impersonate yourself"))
+	}
+
+	user, err := c.userClient.GetUserDetail(ctx, request.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if !user.IsActive {
+		utils.LogEventError(span, errors.New("user is deactivated"))
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("user is deactivated"))
+	}
+
+	if err := c.checkInstitutionActive(ctx, user.InstitutionID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	target := &model.JwtCustomClaims{Name: user.Username, Role: user.RoleID, Amr: session.Amr}
+
+	roles, err := c.roleController.GetUserRoles(ctx, target)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if !roles[0].IsActive {
+		utils.LogEventError(span, errors.New("role is inactive"))
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("role is inactive"))
+	}
+
+	if _, scoped := utils.GetTenant(ctx); scoped {
+		for _, userRole := range roles {
+			if userRole.IsSuperAdmin {
+				utils.LogEventError(span, errors.New("cannot impersonate a super admin"))
+				return nil, model.ThrowError(http.StatusForbidden, errors.New("only a super admin can impersonate a super admin"))
+			}
+		}
+	}
+
+	if err := c.roleController.Authorize(ctx, target, impersonationRoute, http.MethodPost); err == nil {
+		utils.LogEventError(span, errors.New("user may impersonate"))
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("users who may impersonate cannot be impersonated"))
+	}
+
+	role, err := c.roleController.GetRolesMenuRoleMapping(ctx, roles)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var roleIDs []string
+	for _, userRole := range roles {
+		roleIDs = append(roleIDs, userRole.Id)
+	}
+
+	readOnly := true
+	if value, _ := c.getParamValue(ctx, impersonationReadOnlyParam); value == "false" && request.ReadOnly != nil {
+		readOnly = *request.ReadOnly
+	}
+
+	expiredAt := time.Now().Add(time.Duration(c.getParamInt(ctx, impersonationMinutesParam, 15)) * time.Minute)
+	if actorExpiredAt := time.Unix(session.ExpiredAt, 0); session.ExpiredAt > 0 && actorExpiredAt.Before(expiredAt) {
+		expiredAt = actorExpiredAt
+	}
+
+	sessionID := uuid.New().String()
+
+	actor := &model.ActorClaim{
+		Subject:   session.Username,
+		SessionID: session.SessionID,
+		ReadOnly:  readOnly,
+	}
+
+	token, err := c.userClient.CreateImpersonationToken(ctx, user, sessionID, session.Amr, actor, expiredAt)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	c.AuditImpersonation(ctx, &model.ImpersonationAudit{
+		Actor:     session.Username,
+		Username:  user.Username,
+		SessionID: sessionID,
+		Status:    http.StatusOK,
+		Reason:    request.Reason,
+		IPAddress: request.IPAddress,
+		CreatedAt: time.Now(),
+	})
+
+	utils.LogEvent(span, "Response", sessionID)
+
+	return &model.ResponseImpersonation{
+		ResponseLogin: &model.ResponseLogin{
+			Username:      user.Username,
+			Fullname:      user.Fullname,
+			Shortname:     user.Shortname,
+			Role:          user.RoleID,
+			Roles:         roleIDs,
+			Token:         token,
+			InstitutionID: user.InstitutionID,
+			MenuMapping:   role,
+		},
+		ImpersonatedBy: session.Username,
+		ReadOnly:       readOnly,
+		ExpiredAt:      expiredAt.Unix(),
+	}, nil
+}
+
+// AuditImpersonation records a request made while impersonating. A failure is logged but
+// never fails the request itself.
+func (c *UserController) AuditImpersonation(ctx context.Context, audit *model.ImpersonationAudit) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: AuditImpersonation")
+	defer span.Finish()
+
+	if len(audit.Path) > 500 {
+		audit.Path = audit.Path[:500]
+	}
+	if len(audit.Reason) > 500 {
+		audit.Reason = audit.Reason[:500]
+	}
+
+	if err := c.impersonationClient.CreateImpersonationAudit(ctx, audit); err != nil {
+		utils.LogEventError(span, err)
+	}
+}
+
+func (c *UserController) GetImpersonationAudit(ctx context.Context, request *model.RequestImpersonationAudit) (*model.ResponsePagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetImpersonationAudit")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	filter := &model.ImpersonationAuditFilter{
+		Actor:     request.Actor,
+		Username:  request.Username,
+		SessionID: request.SessionID,
+	}
+
+	var err error
+
+	if filter.From, err = parseFilterTime(request.From, false); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("invalid from: %w", err))
+	}
+
+	if filter.To, err = parseFilterTime(request.To, true); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("invalid to: %w", err))
+	}
+
+	page := request.Page
+	if page < 1 {
+		page = 1
+	}
+
+	limit := request.Limit
+	if limit < 1 {
+		limit = loginHistoryDefaultLimit
+	}
+	if limit > loginHistoryMaxLimit {
+		limit = loginHistoryMaxLimit
+	}
+
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	items, total, err := c.impersonationClient.GetImpersonationAudit(ctx, filter)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return &model.ResponsePagination{
+		Items: items,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}, nil
+}
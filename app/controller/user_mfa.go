@@ -0,0 +1,451 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	mfaChallengeKey        = "mfa_challenge:%s"
+	mfaChallengeAttemptKey = "mfa_challenge_attempt:%s"
+	mfaUsedCodeKey         = "mfa_used_code:%s:%d"
+
+	mfaIssuerParam = "MFA_ISSUER"
+
+	mfaChallengeExpiry     = 5 * time.Minute
+	mfaChallengeMaxAttempt = 5
+	mfaUsedCodeExpiry      = 2 * time.Minute
+	mfaRecoveryCodeCount   = 10
+)
+
+var errMfaChallengeExpired = model.ThrowError(http.StatusUnauthorized, errors.New("two-factor challenge is invalid or has expired"))
+
+// createMfaChallenge stores a short-lived challenge for a user whose password was accepted
+// but who still has to present a second factor.
+func (c *UserController) createMfaChallenge(ctx context.Context, username string) (*model.ResponseLogin, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	err = c.redis.Set(ctx, fmt.Sprintf(mfaChallengeKey, utils.HashToken(token)), username, mfaChallengeExpiry).Err()
+	if err != nil {
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	response := &model.ResponseLogin{
+		Username:       username,
+		MfaRequired:    true,
+		ChallengeToken: token,
+	}
+
+	return response, nil
+}
+
+func (c *UserController) LoginMfa(ctx context.Context, request *model.RequestLoginMfa) (response *model.ResponseLogin, err error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: LoginMfa")
+	defer span.Finish()
+
+	history := &model.LoginHistory{
+		Method:    model.AuthMethodOTP,
+		IPAddress: request.IPAddress,
+		UserAgent: request.UserAgent,
+	}
+	defer func() { c.recordLogin(ctx, history, err) }()
+
+	tokenHash := utils.HashToken(request.ChallengeToken)
+	challengeKey := fmt.Sprintf(mfaChallengeKey, tokenHash)
+
+	username, err := c.redis.Get(ctx, challengeKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			utils.LogEventError(span, errMfaChallengeExpired)
+			return nil, errMfaChallengeExpired
+		}
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Request", username)
+
+	history.Username = username
+
+	if err := c.checkLoginAllowed(ctx, username, request.IPAddress); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	attemptKey := fmt.Sprintf(mfaChallengeAttemptKey, tokenHash)
+	attempt, err := c.redis.Incr(ctx, attemptKey).Result()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if attempt == 1 {
+		c.redis.Expire(ctx, attemptKey, mfaChallengeExpiry)
+	}
+
+	if attempt > mfaChallengeMaxAttempt {
+		c.redis.Del(ctx, challengeKey, attemptKey)
+		utils.LogEventError(span, errLoginLocked)
+		return nil, errLoginLocked
+	}
+
+	mfa, err := c.mfaClient.GetUserMfa(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if mfa == nil || !mfa.IsEnabled {
+		c.redis.Del(ctx, challengeKey, attemptKey)
+		utils.LogEventError(span, errMfaChallengeExpired)
+		return nil, errMfaChallengeExpired
+	}
+
+	if err := c.verifyMfa(ctx, mfa, request.Code, request.RecoveryCode); err != nil {
+		// Failed second factors count towards the same lockout as failed passwords, so new
+		// challenges don't give a fresh set of guesses at the code.
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusUnauthorized {
+			if err := c.recordLoginFailure(ctx, username, request.IPAddress); err != nil {
+				utils.LogEventError(span, err)
+			}
+		}
+
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	c.redis.Del(ctx, challengeKey, attemptKey)
+
+	if err := c.clearLoginFailure(ctx, loginScopeUser, username); err != nil {
+		utils.LogEventError(span, err)
+	}
+
+	user, err := c.userClient.GetUserDetail(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	history.SessionID = uuid.New().String()
+
+	response, err = c.issueSession(ctx, user, history.SessionID, "", []string{model.AuthMethodPassword, model.AuthMethodOTP})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response.Username)
+
+	return response, nil
+}
+
+// verifyMfa accepts either a TOTP code, which can be used only once, or an unused recovery code.
+func (c *UserController) verifyMfa(ctx context.Context, mfa *model.UserMfa, code string, recoveryCode string) error {
+	if code != "" {
+		return c.verifyTOTP(ctx, mfa, code)
+	}
+
+	if recoveryCode == "" {
+		return model.ThrowError(http.StatusBadRequest, errors.New("code or recovery_code is required"))
+	}
+
+	ok, err := c.mfaClient.UseRecoveryCode(ctx, mfa.Username, hashRecoveryCode(recoveryCode))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return model.ThrowError(http.StatusUnauthorized, errors.New("invalid recovery code"))
+	}
+
+	return nil
+}
+
+func (c *UserController) verifyTOTP(ctx context.Context, mfa *model.UserMfa, code string) error {
+	step, ok := utils.ValidateTOTP(mfa.Secret, code, time.Now())
+	if !ok {
+		return model.ThrowError(http.StatusUnauthorized, errors.New("invalid two-factor code"))
+	}
+
+	fresh, err := c.redis.SetNX(ctx, fmt.Sprintf(mfaUsedCodeKey, mfa.Username, step), 1, mfaUsedCodeExpiry).Result()
+	if err != nil {
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if !fresh {
+		return model.ThrowError(http.StatusUnauthorized, errors.New("two-factor code has already been used"))
+	}
+
+	return nil
+}
+
+// generateRecoveryCode returns the recovery codes to show to the user once, along with the
+// hashes to store.
+func generateRecoveryCode() ([]string, []string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := base32.StdEncoding.EncodeToString(b)
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return utils.HashToken(code)
+}
+
+func (c *UserController) EnrollMfa(ctx context.Context) (*model.ResponseMfaEnroll, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: EnrollMfa")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	mfa, err := c.mfaClient.GetUserMfa(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if mfa != nil && mfa.IsEnabled {
+		utils.LogEventError(span, errors.New("two-factor authentication is already enabled"))
+		return nil, model.ThrowError(http.StatusConflict, errors.New("two-factor authentication is already enabled"))
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	err = c.mfaClient.SaveUserMfa(ctx, &model.UserMfa{
+		Username:  session.Username,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	issuer, err := c.getParamValue(ctx, mfaIssuerParam)
+	if err != nil || issuer == "" {
+		issuer = "Face Recognition"
+	}
+
+	response := &model.ResponseMfaEnroll{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(issuer, session.Username, secret),
+	}
+
+	utils.LogEvent(span, "Response", "Success Enroll Two-Factor Authentication")
+
+	return response, nil
+}
+
+func (c *UserController) ActivateMfa(ctx context.Context, request *model.RequestMfaCode) (*model.ResponseMfaRecoveryCode, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ActivateMfa")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	mfa, err := c.mfaClient.GetUserMfa(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if mfa == nil {
+		utils.LogEventError(span, errors.New("two-factor authentication is not enrolled"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("two-factor authentication is not enrolled"))
+	}
+
+	if mfa.IsEnabled {
+		utils.LogEventError(span, errors.New("two-factor authentication is already enabled"))
+		return nil, model.ThrowError(http.StatusConflict, errors.New("two-factor authentication is already enabled"))
+	}
+
+	if err := c.verifyTOTP(ctx, mfa, request.Code); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCode()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if err := c.mfaClient.EnableUserMfa(ctx, session.Username, hashes); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	// Other sessions were authenticated with the password only.
+	if err := c.revokeAllSessions(ctx, session.Username, session.SessionID); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Activate Two-Factor Authentication")
+
+	return &model.ResponseMfaRecoveryCode{RecoveryCodes: codes}, nil
+}
+
+func (c *UserController) RegenerateRecoveryCode(ctx context.Context, request *model.RequestMfaCode) (*model.ResponseMfaRecoveryCode, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RegenerateRecoveryCode")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	mfa, err := c.mfaClient.GetUserMfa(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if mfa == nil || !mfa.IsEnabled {
+		utils.LogEventError(span, errors.New("two-factor authentication is not enabled"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("two-factor authentication is not enabled"))
+	}
+
+	if err := c.verifyTOTP(ctx, mfa, request.Code); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCode()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if err := c.mfaClient.ReplaceRecoveryCode(ctx, session.Username, hashes); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", "Success Regenerate Recovery Code")
+
+	return &model.ResponseMfaRecoveryCode{RecoveryCodes: codes}, nil
+}
+
+func (c *UserController) DisableMfa(ctx context.Context, request *model.RequestMfaCode) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DisableMfa")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	role, err := c.roleController.GetRoleByID(ctx, session.RoleID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if role.MfaRequired {
+		utils.LogEventError(span, errors.New("two-factor authentication is required for this role"))
+		return model.ThrowError(http.StatusForbidden, errors.New("two-factor authentication is required for this role"))
+	}
+
+	mfa, err := c.mfaClient.GetUserMfa(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if mfa == nil {
+		utils.LogEventError(span, errors.New("two-factor authentication is not enrolled"))
+		return model.ThrowError(http.StatusBadRequest, errors.New("two-factor authentication is not enrolled"))
+	}
+
+	if mfa.IsEnabled {
+		if err := c.verifyMfa(ctx, mfa, request.Code, request.RecoveryCode); err != nil {
+			utils.LogEventError(span, err)
+			return err
+		}
+	}
+
+	if err := c.mfaClient.DeleteUserMfa(ctx, session.Username); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Disable Two-Factor Authentication")
+
+	return nil
+}
+
+// ResetUserMfa removes the enrollment of a user who lost their authenticator, so they can
+// sign in with the password and enroll again.
+func (c *UserController) ResetUserMfa(ctx context.Context, username string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ResetUserMfa")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	if _, err := c.userClient.GetUserDetail(ctx, username); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.mfaClient.DeleteUserMfa(ctx, username); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.revokeAllSessions(ctx, username, ""); err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Reset Two-Factor Authentication")
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/app/config"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	oidcStateKey    = "oidc_state:%s"
+	oidcStateExpiry = 10 * time.Minute
+
+	oidcProviderPrefix = "oidc:"
+)
+
+// oidcMfaMethods are amr values from the identity provider that count as a second factor.
+var oidcMfaMethods = []string{"mfa", "otp", "hwk", "swk", "sms"}
+
+func (c *UserController) StartOIDCLogin(ctx context.Context, institutionID string) (*model.ResponseOIDCLogin, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: StartOIDCLogin")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	provider, err := c.oidcClient.GetProvider(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var values [3]string
+	for i := range values {
+		values[i], err = utils.GenerateRandomToken(32)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, model.ThrowError(http.StatusInternalServerError, err)
+		}
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authorizationURL, err := c.oidcClient.GetAuthorizationURL(ctx, provider, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	stateJSON, err := json.Marshal(&model.OIDCState{
+		InstitutionID: institutionID,
+		CodeVerifier:  verifier,
+		Nonce:         nonce,
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if err := c.redis.Set(ctx, fmt.Sprintf(oidcStateKey, utils.HashToken(state)), stateJSON, oidcStateExpiry).Err(); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Start OIDC Login")
+
+	return &model.ResponseOIDCLogin{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+	}, nil
+}
+
+// OIDCCallback completes the authorization code flow started by StartOIDCLogin and logs in
+// the user linked to, or provisioned for, the identity provider account.
+func (c *UserController) OIDCCallback(ctx context.Context, request *model.RequestOIDCCallback) (response *model.ResponseLogin, err error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: OIDCCallback")
+	defer span.Finish()
+
+	history := &model.LoginHistory{
+		Method:    model.AuthMethodOIDC,
+		IPAddress: request.IPAddress,
+		UserAgent: request.UserAgent,
+	}
+	defer func() { c.recordLogin(ctx, history, err) }()
+
+	stateJSON, err := c.redis.GetDel(ctx, fmt.Sprintf(oidcStateKey, utils.HashToken(request.State))).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			utils.LogEventError(span, errors.New("login state is invalid or has expired"))
+			return nil, model.ThrowError(http.StatusUnauthorized, errors.New("login state is invalid or has expired"))
+		}
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	var state model.OIDCState
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Request", state.InstitutionID)
+
+	provider, err := c.oidcClient.GetProvider(ctx, state.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	identity, err := c.oidcClient.ExchangeCode(ctx, provider, request.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	history.Username = identity.Username
+	if history.Username == "" {
+		history.Username = identity.Email
+	}
+	history.InstitutionID = provider.InstitutionID
+
+	user, err := c.resolveOIDCUser(ctx, provider, identity)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	history.Username = user.Username
+
+	amr := []string{model.AuthMethodOIDC}
+	for _, method := range identity.Amr {
+		if utils.Contains(oidcMfaMethods, method) {
+			amr = append(amr, model.AuthMethodOTP)
+			break
+		}
+	}
+
+	history.SessionID = uuid.New().String()
+
+	response, err = c.issueSession(ctx, user, history.SessionID, "", amr)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response.Username)
+
+	return response, nil
+}
+
+// resolveOIDCUser finds the user linked to an identity. Unlinked identities are linked by
+// verified email within the provider's institution or, when enabled, provisioned. The role
+// is kept in sync with the provider's role rules.
+func (c *UserController) resolveOIDCUser(ctx context.Context, provider *config.OIDCProvider, identity *model.OIDCIdentity) (*model.UserDetail, error) {
+	providerName := oidcProviderPrefix + provider.InstitutionID
+	roleID := matchOIDCRole(provider, identity)
+
+	link, err := c.userClient.GetUserIdentity(ctx, providerName, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *model.UserDetail
+
+	if link != nil {
+		user, err = c.userClient.GetUserDetail(ctx, link.Username)
+		if err != nil {
+			return nil, err
+		}
+	} else if identity.Email != "" && identity.EmailVerified {
+		user, err = c.userClient.GetUserByEmail(ctx, identity.Email)
+		if err != nil {
+			if data, ok := err.(*model.ErrorResponse); !ok || data.Code != http.StatusNotFound {
+				return nil, err
+			}
+			user = nil
+		}
+
+		if user != nil {
+			if user.InstitutionID != provider.InstitutionID {
+				return nil, model.ThrowError(http.StatusForbidden, errors.New("account belongs to another institution"))
+			}
+
+			err = c.userClient.CreateUserIdentity(ctx, &model.UserIdentity{
+				Provider: providerName,
+				Subject:  identity.Subject,
+				Username: user.Username,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if user == nil {
+		return c.provisionOIDCUser(ctx, provider, identity, roleID)
+	}
+
+	if roleID != "" && roleID != user.RoleID {
+		if err := c.userClient.UpdateUserRole(ctx, user.Username, roleID, model.SystemActor); err != nil {
+			return nil, err
+		}
+
+		// Tokens issued before carry the previous role.
+		if err := c.revokeAllSessions(ctx, user.Username, ""); err != nil {
+			return nil, model.ThrowError(http.StatusInternalServerError, err)
+		}
+
+		user.RoleID = roleID
+	}
+
+	return user, nil
+}
+
+func (c *UserController) provisionOIDCUser(ctx context.Context, provider *config.OIDCProvider, identity *model.OIDCIdentity, roleID string) (*model.UserDetail, error) {
+	if !provider.AutoProvision {
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("no account is linked to this identity"))
+	}
+
+	if roleID == "" {
+		roleID = provider.DefaultRoleID
+	}
+
+	if roleID == "" {
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("no role could be assigned to this identity"))
+	}
+
+	username := identity.Username
+	if username == "" {
+		username = identity.Email
+	}
+
+	if username == "" {
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("identity provider returned no username or email"))
+	}
+
+	fullname := identity.Name
+	if fullname == "" {
+		fullname = username
+	}
+
+	user := &model.User{
+		UserDetail: model.UserDetail{
+			Username:      username,
+			Email:         identity.Email,
+			Fullname:      fullname,
+			Shortname:     username,
+			RoleID:        roleID,
+			InstitutionID: provider.InstitutionID,
+			IsActive:      true,
+			AuthSource:    model.AuthSourceOIDC,
+		},
+		Password: model.UnusablePassword,
+	}
+
+	err := c.userClient.CreateUserWithIdentity(ctx, user, &model.UserIdentity{
+		Provider: oidcProviderPrefix + provider.InstitutionID,
+		Subject:  identity.Subject,
+		Username: username,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user.UserDetail, nil
+}
+
+// matchOIDCRole returns the role of the first rule matching the identity, or "" when none
+// does. Email rules only apply to verified emails.
+func matchOIDCRole(provider *config.OIDCProvider, identity *model.OIDCIdentity) string {
+	email, domain := "", ""
+	if identity.EmailVerified {
+		email = identity.Email
+	}
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		domain = email[i+1:]
+	}
+
+	for _, rule := range provider.RoleRules {
+		switch rule.Claim {
+		case "groups":
+			if utils.Contains(identity.Groups, rule.Value) {
+				return rule.RoleID
+			}
+		case "email":
+			if email != "" && strings.EqualFold(email, rule.Value) {
+				return rule.RoleID
+			}
+		case "email_domain":
+			if domain != "" && strings.EqualFold(domain, rule.Value) {
+				return rule.RoleID
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,342 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"face-recognition-svc/app/client"
+	"face-recognition-svc/app/config"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// fakeOIDCClient stands in for the identity provider. It keeps the PKCE challenge of the
+// authorization request and refuses a code exchange with a verifier that doesn't match it.
+type fakeOIDCClient struct {
+	provider *config.OIDCProvider
+	identity *model.OIDCIdentity
+
+	challenge string
+	verifier  string
+	nonce     string
+}
+
+func (f *fakeOIDCClient) GetProvider(ctx context.Context, institutionID string) (*config.OIDCProvider, error) {
+	if institutionID != f.provider.InstitutionID {
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("identity provider not configured for institution"))
+	}
+	return f.provider, nil
+}
+
+func (f *fakeOIDCClient) GetAuthorizationURL(ctx context.Context, provider *config.OIDCProvider, state string, nonce string, codeChallenge string) (string, error) {
+	f.challenge = codeChallenge
+	return "https://idp.example.com/authorize?state=" + url.QueryEscape(state) + "&nonce=" + url.QueryEscape(nonce), nil
+}
+
+func (f *fakeOIDCClient) ExchangeCode(ctx context.Context, provider *config.OIDCProvider, code string, codeVerifier string, nonce string) (*model.OIDCIdentity, error) {
+	f.verifier, f.nonce = codeVerifier, nonce
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != f.challenge {
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("authorization code exchange failed"))
+	}
+
+	if f.identity == nil {
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid id_token"))
+	}
+
+	return f.identity, nil
+}
+
+// fakeUserClient keeps users and their linked identities in memory. Methods the OIDC flow
+// doesn't call panic through the nil embedded interface.
+type fakeUserClient struct {
+	client.InterfaceUserClient
+
+	users       map[string]*model.UserDetail
+	identities  map[string]*model.UserIdentity
+	roleUpdates []string
+}
+
+func newFakeUserClient(users ...*model.UserDetail) *fakeUserClient {
+	f := &fakeUserClient{
+		users:      map[string]*model.UserDetail{},
+		identities: map[string]*model.UserIdentity{},
+	}
+	for _, user := range users {
+		f.users[user.Username] = user
+	}
+	return f
+}
+
+func (f *fakeUserClient) GetUserDetail(ctx context.Context, username string) (*model.UserDetail, error) {
+	user, ok := f.users[username]
+	if !ok {
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+	return user, nil
+}
+
+func (f *fakeUserClient) GetUserByEmail(ctx context.Context, email string) (*model.UserDetail, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+}
+
+func (f *fakeUserClient) UpdateUserRole(ctx context.Context, username string, roleID string, updatedBy string) error {
+	f.users[username].RoleID = roleID
+	f.roleUpdates = append(f.roleUpdates, username+":"+roleID+":"+updatedBy)
+	return nil
+}
+
+func (f *fakeUserClient) GetUserIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	return f.identities[provider+" "+subject], nil
+}
+
+func (f *fakeUserClient) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	f.identities[identity.Provider+" "+identity.Subject] = identity
+	return nil
+}
+
+func (f *fakeUserClient) CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	if _, ok := f.users[user.Username]; ok {
+		return model.ThrowError(http.StatusConflict, errors.New("username already exists"))
+	}
+	f.users[user.Username] = &user.UserDetail
+	return f.CreateUserIdentity(ctx, identity)
+}
+
+func testOIDCProvider() *config.OIDCProvider {
+	return &config.OIDCProvider{
+		InstitutionID: "inst-1",
+		AutoProvision: true,
+		DefaultRoleID: "viewer",
+		RoleRules: []config.OIDCRoleRule{
+			{Claim: "groups", Value: "face-admins", RoleID: "admin"},
+			{Claim: "email", Value: "boss@example.com", RoleID: "manager"},
+			{Claim: "email_domain", Value: "operators.example.com", RoleID: "operator"},
+		},
+	}
+}
+
+func TestMatchOIDCRole(t *testing.T) {
+	provider := testOIDCProvider()
+
+	tests := []struct {
+		name     string
+		identity *model.OIDCIdentity
+		want     string
+	}{
+		{
+			name:     "group",
+			identity: &model.OIDCIdentity{Groups: []string{"staff", "face-admins"}, Email: "boss@example.com", EmailVerified: true},
+			want:     "admin",
+		},
+		{
+			name:     "email",
+			identity: &model.OIDCIdentity{Email: "Boss@Example.com", EmailVerified: true},
+			want:     "manager",
+		},
+		{
+			name:     "email domain",
+			identity: &model.OIDCIdentity{Email: "jane@operators.example.com", EmailVerified: true},
+			want:     "operator",
+		},
+		{
+			name:     "unverified email",
+			identity: &model.OIDCIdentity{Email: "boss@example.com"},
+			want:     "",
+		},
+		{
+			name:     "no match",
+			identity: &model.OIDCIdentity{Groups: []string{"staff"}, Email: "jane@example.com", EmailVerified: true},
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchOIDCRole(provider, tt.identity); got != tt.want {
+				t.Errorf("matchOIDCRole() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	ctx := context.Background()
+
+	rdb, fake := newFakeRedis()
+	oidc := &fakeOIDCClient{provider: testOIDCProvider()}
+	c := &UserController{redis: rdb, oidcClient: oidc}
+
+	login, err := c.StartOIDCLogin(ctx, "inst-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.data[fmt.Sprintf(oidcStateKey, utils.HashToken(login.State))]; !ok {
+		t.Fatal("login state is not stored under the hash of the state")
+	}
+
+	_, err = c.OIDCCallback(ctx, &model.RequestOIDCCallback{State: "forged", Code: "code"})
+	if data, ok := err.(*model.ErrorResponse); !ok || data.Code != http.StatusUnauthorized || oidc.verifier != "" {
+		t.Fatalf("forged state: err = %v, code exchanged = %t", err, oidc.verifier != "")
+	}
+
+	// The stub has no identity to return, the exchange itself still has to get the
+	// verifier and nonce of this login.
+	c.OIDCCallback(ctx, &model.RequestOIDCCallback{State: login.State, Code: "code"})
+
+	if oidc.verifier == "" {
+		t.Fatal("code was not exchanged for a valid state")
+	}
+	if query, _ := url.Parse(login.AuthorizationURL); query.Query().Get("nonce") != oidc.nonce {
+		t.Errorf("nonce = %q, want the nonce of the authorization request", oidc.nonce)
+	}
+
+	oidc.verifier = ""
+
+	_, err = c.OIDCCallback(ctx, &model.RequestOIDCCallback{State: login.State, Code: "code"})
+	if data, ok := err.(*model.ErrorResponse); !ok || data.Code != http.StatusUnauthorized || oidc.verifier != "" {
+		t.Fatalf("reused state: err = %v, code exchanged = %t", err, oidc.verifier != "")
+	}
+}
+
+func TestResolveOIDCUser(t *testing.T) {
+	providerName := oidcProviderPrefix + "inst-1"
+
+	tests := []struct {
+		name       string
+		provider   func(*config.OIDCProvider)
+		users      []*model.UserDetail
+		identities []*model.UserIdentity
+		identity   model.OIDCIdentity
+		wantCode   int
+		wantUser   string
+		wantRole   string
+		wantLinked bool
+		wantUpdate []string
+	}{
+		{
+			name:       "linked identity",
+			users:      []*model.UserDetail{{Username: "jane", RoleID: "viewer", InstitutionID: "inst-1", IsActive: true}},
+			identities: []*model.UserIdentity{{Provider: providerName, Subject: "sub-1", Username: "jane"}},
+			identity:   model.OIDCIdentity{Subject: "sub-1", Username: "someone-else"},
+			wantUser:   "jane",
+			wantRole:   "viewer",
+			wantLinked: true,
+		},
+		{
+			name:       "role rule updates linked user",
+			users:      []*model.UserDetail{{Username: "jane", RoleID: "viewer", InstitutionID: "inst-1", IsActive: true}},
+			identities: []*model.UserIdentity{{Provider: providerName, Subject: "sub-1", Username: "jane"}},
+			identity:   model.OIDCIdentity{Subject: "sub-1", Groups: []string{"face-admins"}},
+			wantUser:   "jane",
+			wantRole:   "admin",
+			wantLinked: true,
+			wantUpdate: []string{"jane:admin:" + model.SystemActor},
+		},
+		{
+			name:       "verified email links existing user",
+			users:      []*model.UserDetail{{Username: "jane", Email: "jane@example.com", RoleID: "viewer", InstitutionID: "inst-1", IsActive: true}},
+			identity:   model.OIDCIdentity{Subject: "sub-1", Username: "jdoe", Email: "jane@example.com", EmailVerified: true},
+			wantUser:   "jane",
+			wantRole:   "viewer",
+			wantLinked: true,
+		},
+		{
+			name:     "verified email of another institution",
+			users:    []*model.UserDetail{{Username: "jane", Email: "jane@example.com", RoleID: "viewer", InstitutionID: "inst-2", IsActive: true}},
+			identity: model.OIDCIdentity{Subject: "sub-1", Email: "jane@example.com", EmailVerified: true},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:       "unverified email provisions a new user",
+			users:      []*model.UserDetail{{Username: "jane", Email: "jane@example.com", RoleID: "admin", InstitutionID: "inst-1", IsActive: true}},
+			identity:   model.OIDCIdentity{Subject: "sub-1", Email: "jane@example.com"},
+			wantUser:   "jane@example.com",
+			wantRole:   "viewer",
+			wantLinked: true,
+		},
+		{
+			name:       "provisions with default role",
+			identity:   model.OIDCIdentity{Subject: "sub-1", Username: "jdoe", Email: "jane@example.com", EmailVerified: true},
+			wantUser:   "jdoe",
+			wantRole:   "viewer",
+			wantLinked: true,
+		},
+		{
+			name:       "provisions with role rule and email as username",
+			identity:   model.OIDCIdentity{Subject: "sub-1", Email: "jane@operators.example.com", EmailVerified: true},
+			wantUser:   "jane@operators.example.com",
+			wantRole:   "operator",
+			wantLinked: true,
+		},
+		{
+			name:     "auto provision disabled",
+			provider: func(p *config.OIDCProvider) { p.AutoProvision = false },
+			identity: model.OIDCIdentity{Subject: "sub-1", Username: "jdoe"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "no role to assign",
+			provider: func(p *config.OIDCProvider) { p.DefaultRoleID = "" },
+			identity: model.OIDCIdentity{Subject: "sub-1", Username: "jdoe"},
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := testOIDCProvider()
+			if tt.provider != nil {
+				tt.provider(provider)
+			}
+
+			rdb, _ := newFakeRedis()
+			users := newFakeUserClient(tt.users...)
+			for _, identity := range tt.identities {
+				users.CreateUserIdentity(context.Background(), identity)
+			}
+			c := &UserController{redis: rdb, userClient: users}
+
+			user, err := c.resolveOIDCUser(context.Background(), provider, &tt.identity)
+
+			if tt.wantCode != 0 {
+				if data, ok := err.(*model.ErrorResponse); !ok || data.Code != tt.wantCode {
+					t.Fatalf("err = %v, want %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if user.Username != tt.wantUser || user.RoleID != tt.wantRole {
+				t.Errorf("user = %s with role %s, want %s with role %s", user.Username, user.RoleID, tt.wantUser, tt.wantRole)
+			}
+
+			if user.InstitutionID != "inst-1" {
+				t.Errorf("institution = %q, want inst-1", user.InstitutionID)
+			}
+
+			link := users.identities[providerName+" "+tt.identity.Subject]
+			if tt.wantLinked && (link == nil || link.Username != tt.wantUser) {
+				t.Errorf("identity linked to %+v, want %s", link, tt.wantUser)
+			}
+
+			if fmt.Sprint(users.roleUpdates) != fmt.Sprint(tt.wantUpdate) {
+				t.Errorf("role updates = %v, want %v", users.roleUpdates, tt.wantUpdate)
+			}
+		})
+	}
+}
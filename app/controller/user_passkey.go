@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	passkeyRegistrationKey = "passkey_registration:%s"
+	passkeyLoginKey        = "passkey_login:%s"
+
+	passkeyCeremonyExpiry = 5 * time.Minute
+)
+
+var errPasskeyChallengeExpired = model.ThrowError(http.StatusUnauthorized, errors.New("passkey challenge is invalid or has expired"))
+
+func (c *UserController) GetPasskey(ctx context.Context) ([]*model.Passkey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetPasskey")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	response, err := c.passkeyClient.GetPasskeyByUser(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// BeginPasskeyRegistration starts registering a passkey for the caller. Only one
+// registration can be pending per user, starting another replaces it.
+func (c *UserController) BeginPasskeyRegistration(ctx context.Context) (*protocol.CredentialCreation, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: BeginPasskeyRegistration")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	user, passkeys, err := c.getPasskeyUser(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	creation, ceremony, err := c.passkeyClient.BeginRegistration(ctx, user, passkeys)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := c.saveCeremony(ctx, fmt.Sprintf(passkeyRegistrationKey, session.Username), ceremony); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", "Success Begin Passkey Registration")
+
+	return creation, nil
+}
+
+func (c *UserController) FinishPasskeyRegistration(ctx context.Context, request *model.RequestPasskeyRegister) (*model.Passkey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: FinishPasskeyRegistration")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	ceremony, err := c.takeCeremony(ctx, fmt.Sprintf(passkeyRegistrationKey, session.Username))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	user, passkeys, err := c.getPasskeyUser(ctx, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	passkey, err := c.passkeyClient.FinishRegistration(ctx, user, passkeys, ceremony, request.Credential)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	existing, err := c.passkeyClient.GetPasskeyByCredentialID(ctx, passkey.CredentialID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if existing != nil {
+		utils.LogEventError(span, errors.New("passkey is already registered"))
+		return nil, model.ThrowError(http.StatusConflict, errors.New("passkey is already registered"))
+	}
+
+	passkey.Name = request.Name
+	if passkey.Name == "" {
+		passkey.Name = fmt.Sprintf("Passkey %d", len(passkeys)+1)
+	}
+
+	if err := c.passkeyClient.CreatePasskey(ctx, passkey); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", passkey.ID)
+
+	return passkey, nil
+}
+
+func (c *UserController) DeletePasskey(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeletePasskey")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	if err := c.passkeyClient.DeletePasskey(ctx, session.Username, id); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Passkey")
+
+	return nil
+}
+
+// BeginPasskeyLogin starts a usernameless login. The challenge token has to be sent back
+// with the assertion to LoginPasskey.
+func (c *UserController) BeginPasskeyLogin(ctx context.Context) (*model.ResponsePasskeyLogin, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: BeginPasskeyLogin")
+	defer span.Finish()
+
+	assertion, ceremony, err := c.passkeyClient.BeginLogin(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if err := c.saveCeremony(ctx, fmt.Sprintf(passkeyLoginKey, utils.HashToken(token)), ceremony); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", "Success Begin Passkey Login")
+
+	return &model.ResponsePasskeyLogin{
+		ChallengeToken: token,
+		Options:        assertion,
+	}, nil
+}
+
+// LoginPasskey verifies an assertion and logs in the owner of the passkey. Passkeys require
+// user verification, so the session counts as multi-factor and skips the TOTP challenge.
+func (c *UserController) LoginPasskey(ctx context.Context, request *model.RequestLoginPasskey) (response *model.ResponseLogin, err error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: LoginPasskey")
+	defer span.Finish()
+
+	history := &model.LoginHistory{
+		Method:    model.AuthMethodPasskey,
+		IPAddress: request.IPAddress,
+		UserAgent: request.UserAgent,
+	}
+	defer func() { c.recordLogin(ctx, history, err) }()
+
+	ceremony, err := c.takeCeremony(ctx, fmt.Sprintf(passkeyLoginKey, utils.HashToken(request.ChallengeToken)))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	passkey, err := c.passkeyClient.FinishLogin(ctx, ceremony, request.Credential)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", passkey.Username)
+
+	history.Username = passkey.Username
+
+	if err := c.passkeyClient.UpdatePasskeyUsage(ctx, passkey); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	user, err := c.userClient.GetUserDetail(ctx, passkey.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	history.SessionID = uuid.New().String()
+
+	response, err = c.issueSession(ctx, user, history.SessionID, "", []string{model.AuthMethodPasskey})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response.Username)
+
+	return response, nil
+}
+
+func (c *UserController) getPasskeyUser(ctx context.Context, username string) (*model.UserDetail, []*model.Passkey, error) {
+	user, err := c.userClient.GetUserDetail(ctx, username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	passkeys, err := c.passkeyClient.GetPasskeyByUser(ctx, username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, passkeys, nil
+}
+
+func (c *UserController) saveCeremony(ctx context.Context, key string, ceremony *webauthn.SessionData) error {
+	ceremonyJSON, err := json.Marshal(ceremony)
+	if err != nil {
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if err := c.redis.Set(ctx, key, ceremonyJSON, passkeyCeremonyExpiry).Err(); err != nil {
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// takeCeremony reads and removes a pending ceremony so each challenge is answered once.
+func (c *UserController) takeCeremony(ctx context.Context, key string) (*webauthn.SessionData, error) {
+	ceremonyJSON, err := c.redis.GetDel(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errPasskeyChallengeExpired
+		}
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	var ceremony webauthn.SessionData
+	if err := json.Unmarshal([]byte(ceremonyJSON), &ceremony); err != nil {
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return &ceremony, nil
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	passwordMinLengthParam  = "PASSWORD_MIN_LENGTH"
+	passwordMinClassesParam = "PASSWORD_MIN_CLASSES"
+	passwordHistoryParam    = "PASSWORD_HISTORY"
+
+	temporaryPasswordLength = 16
+)
+
+// checkPasswordPolicy refuses passwords that are too short or too long, use too few
+// character classes, are on the blocklist, contain the username or match one of the
+// previous password hashes given. All violations are reported at once.
+func (c *UserController) checkPasswordPolicy(ctx context.Context, username string, password string, previous []string) error {
+	minLength := c.getParamInt(ctx, passwordMinLengthParam, 10)
+	minClasses := c.getParamInt(ctx, passwordMinClassesParam, 3)
+
+	var violations []string
+
+	if int64(utf8.RuneCountInString(password)) < minLength {
+		violations = append(violations, fmt.Sprintf("be at least %d characters long", minLength))
+	}
+
+	if len(password) > utils.Passwords.MaxLength() {
+		violations = append(violations, fmt.Sprintf("be at most %d bytes long", utils.Passwords.MaxLength()))
+	}
+
+	var lower, upper, digit, symbol int64
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	if lower+upper+digit+symbol < minClasses {
+		violations = append(violations, fmt.Sprintf("contain at least %d of lowercase letters, uppercase letters, digits and symbols", minClasses))
+	}
+
+	if utils.Passwords.IsBlocked(password) {
+		violations = append(violations, "not be a commonly used password")
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, "not contain the username")
+	}
+
+	if len(violations) > 0 {
+		return model.ThrowError(http.StatusBadRequest, errors.New("password must "+strings.Join(violations, ", ")))
+	}
+
+	for _, hash := range previous {
+		if ok, _ := utils.Passwords.Verify(hash, password); ok {
+			return model.ThrowError(http.StatusBadRequest, fmt.Errorf("password must not be one of the last %d passwords", len(previous)))
+		}
+	}
+
+	return nil
+}
+
+// getPreviousPassword returns the current password hash followed by as many earlier ones
+// as needed to cover the PASSWORD_HISTORY parameter.
+func (c *UserController) getPreviousPassword(ctx context.Context, username string, currentHash string) ([]string, error) {
+	size := int(c.getParamInt(ctx, passwordHistoryParam, 5))
+	if size <= 0 {
+		return nil, nil
+	}
+
+	previous := []string{currentHash}
+	if size == 1 {
+		return previous, nil
+	}
+
+	history, err := c.userClient.GetPasswordHistory(ctx, username, size-1)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(previous, history...), nil
+}
+
+// hashPassword checks a new password against the policy and hashes it with the current
+// algorithm.
+func (c *UserController) hashPassword(ctx context.Context, username string, password string, previous []string) (string, error) {
+	if err := c.checkPasswordPolicy(ctx, username, password, previous); err != nil {
+		return "", err
+	}
+
+	hash, err := utils.Passwords.Hash(password)
+	if err != nil {
+		return "", model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return hash, nil
+}
+
+// rehashPassword upgrades the stored hash of a password that was just verified. A failure
+// only means the upgrade is retried on the next login.
+func (c *UserController) rehashPassword(ctx context.Context, user *model.User, password string) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: rehashPassword")
+	defer span.Finish()
+
+	hash, err := utils.Passwords.Hash(password)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return
+	}
+
+	if err := c.userClient.RehashPassword(ctx, user.Username, user.Password, hash); err != nil {
+		utils.LogEventError(span, err)
+	}
+}
@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const userRoleCacheKey = "permission:user_role:%s"
+
+// getActiveUserRole returns the role grants of a user from the permission cache, loading
+// them from the database on a miss. Grants are cached with their expiry and filtered on
+// every read, so an expired grant stops applying without waiting for the cache.
+func (c *RoleController) getActiveUserRole(ctx context.Context, username string) ([]*model.UserRole, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: getActiveUserRole")
+	defer span.Finish()
+
+	key := fmt.Sprintf(userRoleCacheKey, username)
+
+	var grants []*model.UserRole
+
+	cache := c.redis.Get(ctx, key).Val()
+	if cache != "" {
+		if err := json.Unmarshal([]byte(cache), &grants); err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+	} else {
+		var err error
+		grants, err = c.userRoleClient.GetUserRole(ctx, username)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		resJSON, err := json.Marshal(grants)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		if err := c.redis.Set(ctx, key, resJSON, 6*time.Hour).Err(); err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+
+	var response []*model.UserRole
+	for _, grant := range grants {
+		if grant.IsActiveAt(now) {
+			response = append(response, grant)
+		}
+	}
+
+	return response, nil
+}
+
+// GetUserRoles returns the caller's primary role followed by the roles granted to them that
+// are active, still exist and, when they require two-factor authentication, were used with
+// a token that has it. The primary role is returned as is; callers check its status.
+func (c *RoleController) GetUserRoles(ctx context.Context, claims *model.JwtCustomClaims) ([]*model.Role, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetUserRoles")
+	defer span.Finish()
+
+	primary, err := c.GetRoleByID(ctx, claims.Role)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	grants, err := c.getActiveUserRole(ctx, claims.Name)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	roles := []*model.Role{primary}
+	seen := map[string]bool{primary.Id: true}
+
+	for _, grant := range grants {
+		if seen[grant.RoleID] {
+			continue
+		}
+		seen[grant.RoleID] = true
+
+		role, err := c.GetRoleByID(ctx, grant.RoleID)
+		if err != nil {
+			if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+				continue
+			}
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		if !role.IsActive || (role.MfaRequired && !model.HasMultiFactor(claims.Amr)) {
+			continue
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+// GetRolesMenuRoleMapping merges the effective menu mappings of several roles into one row
+// per menu carrying the union of their methods.
+func (c *RoleController) GetRolesMenuRoleMapping(ctx context.Context, roles []*model.Role) ([]*model.MenuRoleMapping, error) {
+	var response []*model.MenuRoleMapping
+	byMenu := map[string]*model.MenuRoleMapping{}
+
+	for _, role := range roles {
+		mappings, err := c.GetEffectiveMenuRoleMapping(ctx, role.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mapping := range mappings {
+			if merged, ok := byMenu[mapping.MenuID]; ok {
+				merged.AccessMethod = mergeAccessMethod(merged.AccessMethod, mapping.AccessMethod)
+				continue
+			}
+
+			byMenu[mapping.MenuID] = mapping
+			response = append(response, mapping)
+		}
+	}
+
+	return response, nil
+}
+
+// GetUserRole lists the role grants of a user that have not expired.
+func (c *RoleController) GetUserRole(ctx context.Context, username string) ([]*model.UserRole, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetUserRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	response, err := c.userRoleClient.GetUserRole(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+// GrantUserRole gives a user a role on top of their primary role, until ExpiresAt when set.
+func (c *RoleController) GrantUserRole(ctx context.Context, request *model.UserRole) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GrantUserRole")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		utils.LogEventError(span, errors.New("expires_at must be in the future"))
+		return model.ThrowError(http.StatusBadRequest, errors.New("expires_at must be in the future"))
+	}
+
+	role, err := c.GetRoleByID(ctx, request.RoleID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusNotFound {
+			return model.ThrowError(http.StatusBadRequest, errors.New("role not found"))
+		}
+		return err
+	}
+
+	if err := c.checkSuperAdminRole(ctx, role, nil); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	request.ID = uuid.New().String()
+	request.CreatedAt = time.Now()
+	request.CreatedBy = session.Username
+
+	utils.LogEvent(span, "Request", request)
+
+	if err := c.userRoleClient.CreateUserRole(ctx, request); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.redis.Del(ctx, fmt.Sprintf(userRoleCacheKey, request.Username)).Err(); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Grant User Role")
+
+	return nil
+}
+
+// RevokeUserRole removes a role grant from a user. It takes effect on the user's next request.
+func (c *RoleController) RevokeUserRole(ctx context.Context, username string, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RevokeUserRole")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]string{"username": username, "id": id})
+
+	grant, err := c.userRoleClient.GetUserRoleByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if grant.Username != username {
+		utils.LogEventError(span, errors.New("role grant belongs to another user"))
+		return model.ThrowError(http.StatusNotFound, errors.New("role grant not found"))
+	}
+
+	role, err := c.GetRoleByID(ctx, grant.RoleID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.checkSuperAdminRole(ctx, role, nil); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.userRoleClient.DeleteUserRole(ctx, id); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if err := c.redis.Del(ctx, fmt.Sprintf(userRoleCacheKey, username)).Err(); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Response", "Success Revoke User Role")
+
+	return nil
+}
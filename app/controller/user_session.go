@@ -0,0 +1,311 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	loginHistoryDefaultLimit = 20
+	loginHistoryMaxLimit     = 100
+
+	maxUserAgentLength = 500
+)
+
+// recordLogin writes a login attempt to the history and, for a successful login, stores
+// where the session was started from next to it. It never fails the login itself.
+func (c *UserController) recordLogin(ctx context.Context, history *model.LoginHistory, err error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: recordLogin")
+	defer span.Finish()
+
+	if history.Username == "" {
+		return
+	}
+
+	switch {
+	case err != nil:
+		history.Outcome = model.LoginOutcomeFailure
+		if data, ok := err.(*model.ErrorResponse); ok && data.Code == http.StatusTooManyRequests {
+			history.Outcome = model.LoginOutcomeLocked
+		}
+		history.Reason = err.Error()
+		history.SessionID = ""
+	case history.Outcome == "":
+		history.Outcome = model.LoginOutcomeSuccess
+	}
+
+	if len(history.UserAgent) > maxUserAgentLength {
+		history.UserAgent = history.UserAgent[:maxUserAgentLength]
+	}
+
+	history.CreatedAt = time.Now()
+
+	if err := c.loginHistoryClient.CreateLoginHistory(ctx, history); err != nil {
+		utils.LogEventError(span, err)
+	}
+
+	if history.Outcome != model.LoginOutcomeSuccess || history.SessionID == "" {
+		return
+	}
+
+	if err := c.describeSession(ctx, history); err != nil {
+		utils.LogEventError(span, err)
+	}
+}
+
+// describeSession adds the login method and client of a session to its entry in the
+// session list, trackSession keeps them on every refresh.
+func (c *UserController) describeSession(ctx context.Context, history *model.LoginHistory) error {
+	key := fmt.Sprintf(userSessionsKey, history.Username)
+
+	cache, err := c.redis.HGet(ctx, key, history.SessionID).Result()
+	if err != nil {
+		return err
+	}
+
+	session := &model.UserSession{}
+	if err := json.Unmarshal([]byte(cache), session); err != nil {
+		return err
+	}
+
+	session.Method = history.Method
+	session.IPAddress = history.IPAddress
+	session.UserAgent = history.UserAgent
+
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return c.redis.HSet(ctx, key, history.SessionID, sessionJSON).Err()
+}
+
+// getSessions returns the live sessions of a user, newest first. Sessions whose refresh
+// token has expired are dropped from the list on the way.
+func (c *UserController) getSessions(ctx context.Context, username string, currentSessionID string) ([]*model.UserSession, error) {
+	key := fmt.Sprintf(userSessionsKey, username)
+
+	cache, err := c.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	sessions := make([]*model.UserSession, 0, len(cache))
+
+	for sessionID, sessionJSON := range cache {
+		live, err := c.redis.Exists(ctx, fmt.Sprintf(refreshTokenKey, sessionID)).Result()
+		if err != nil {
+			return nil, model.ThrowError(http.StatusInternalServerError, err)
+		}
+
+		if live == 0 && sessionID != currentSessionID {
+			c.redis.HDel(ctx, key, sessionID)
+			continue
+		}
+
+		session := &model.UserSession{}
+		if err := json.Unmarshal([]byte(sessionJSON), session); err != nil {
+			return nil, model.ThrowError(http.StatusInternalServerError, err)
+		}
+
+		session.Current = sessionID == currentSessionID
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+
+	return sessions, nil
+}
+
+func (c *UserController) GetMySession(ctx context.Context) ([]*model.UserSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetMySession")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	response, err := c.getSessions(ctx, session.Username, session.SessionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RevokeMySession ends one of the caller's own sessions, which may be the current one.
+func (c *UserController) RevokeMySession(ctx context.Context, sessionID string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RevokeMySession")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	utils.LogEvent(span, "Request", sessionID)
+
+	exists, err := c.redis.HExists(ctx, fmt.Sprintf(userSessionsKey, session.Username), sessionID).Result()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if !exists {
+		utils.LogEventError(span, errors.New("session not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("session not found"))
+	}
+
+	if err := c.revokeSession(ctx, session.Username, sessionID); err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", "Success Revoke Session")
+
+	return nil
+}
+
+func (c *UserController) GetUserSession(ctx context.Context, username string) ([]*model.UserSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetUserSession")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	if _, err := c.userClient.GetUserDetail(ctx, username); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	response, err := c.getSessions(ctx, username, "")
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetMyLoginHistory returns the caller's own login attempts, other filters are ignored.
+func (c *UserController) GetMyLoginHistory(ctx context.Context, request *model.RequestLoginHistory) (*model.ResponsePagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetMyLoginHistory")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Request", session.Username)
+
+	request.Username = session.Username
+	request.InstitutionID = ""
+
+	response, err := c.getLoginHistory(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *UserController) GetLoginHistory(ctx context.Context, request *model.RequestLoginHistory) (*model.ResponsePagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetLoginHistory")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	response, err := c.getLoginHistory(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *UserController) getLoginHistory(ctx context.Context, request *model.RequestLoginHistory) (*model.ResponsePagination, error) {
+	filter := &model.LoginHistoryFilter{
+		Username:      request.Username,
+		InstitutionID: request.InstitutionID,
+		Outcome:       request.Outcome,
+	}
+
+	var err error
+
+	if filter.From, err = parseFilterTime(request.From, false); err != nil {
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("invalid from: %w", err))
+	}
+
+	if filter.To, err = parseFilterTime(request.To, true); err != nil {
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("invalid to: %w", err))
+	}
+
+	page := request.Page
+	if page < 1 {
+		page = 1
+	}
+
+	limit := request.Limit
+	if limit < 1 {
+		limit = loginHistoryDefaultLimit
+	}
+	if limit > loginHistoryMaxLimit {
+		limit = loginHistoryMaxLimit
+	}
+
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	items, total, err := c.loginHistoryClient.GetLoginHistory(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ResponsePagination{
+		Items: items,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}, nil
+}
+
+// parseFilterTime accepts a date or an RFC 3339 time. A date used as the end of a range
+// includes the whole day.
+func parseFilterTime(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return nil, errors.New("expected YYYY-MM-DD or an RFC 3339 time")
+	}
+
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return &t, nil
+}
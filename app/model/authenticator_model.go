@@ -0,0 +1,28 @@
+package model
+
+// Values of users.auth_source, deciding which authenticator checks a user's password.
+const (
+	AuthSourceLocal = "local"
+	AuthSourceLDAP  = "ldap"
+	AuthSourceOIDC  = "oidc"
+)
+
+// UnusablePassword is stored for users that authenticate elsewhere, it never matches a
+// password hash so they cannot log in with a local password.
+const UnusablePassword = "!"
+
+// SystemActor is recorded as updated_by for changes the service makes on its own, such as a
+// role synced from an external authenticator on login.
+const SystemActor = "system"
+
+// ExternalUser is an account verified by an external authenticator such as LDAP. RoleID is
+// set only when the account matched a group mapping, DefaultRoleID applies to new users.
+type ExternalUser struct {
+	Username      string
+	Email         string
+	Fullname      string
+	RoleID        string
+	DefaultRoleID string
+	InstitutionID string
+	AutoProvision bool
+}
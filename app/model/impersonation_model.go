@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// ActorClaim is the "act" claim of an impersonation token: the administrator acting as
+// the subject of the token and the session they started it from.
+type ActorClaim struct {
+	Subject   string `json:"sub"`
+	SessionID string `json:"sid"`
+	ReadOnly  bool   `json:"ro,omitempty"`
+}
+
+// RequestImpersonation starts impersonating Username. ReadOnly is ignored while the
+// IMPERSONATION_READ_ONLY parameter forces every impersonation to be read-only.
+type RequestImpersonation struct {
+	Username string `json:"username"`
+	Reason   string `json:"reason"`
+	ReadOnly *bool  `json:"read_only"`
+
+	IPAddress string `json:"-"`
+}
+
+type ResponseImpersonation struct {
+	*ResponseLogin
+	ImpersonatedBy string `json:"impersonated_by"`
+	ReadOnly       bool   `json:"read_only"`
+	ExpiredAt      int64  `json:"expired_at"`
+}
+
+// ImpersonationAudit is one request made with an impersonation token, or the start of an
+// impersonation when Method is empty.
+type ImpersonationAudit struct {
+	ID       int64  `json:"id" gorm:"column:id"`
+	Actor    string `json:"actor" gorm:"column:actor"`
+	Username string `json:"username" gorm:"column:username"`
+	// InstitutionID is the institution of the impersonated user.
+	InstitutionID string    `json:"institution_id" gorm:"column:institution_id"`
+	SessionID     string    `json:"session_id" gorm:"column:session_id"`
+	Method        string    `json:"method" gorm:"column:method"`
+	Path          string    `json:"path" gorm:"column:path"`
+	Status        int       `json:"status" gorm:"column:status"`
+	Blocked       bool      `json:"blocked" gorm:"column:blocked"`
+	Reason        string    `json:"reason" gorm:"column:reason"`
+	IPAddress     string    `json:"ip_address" gorm:"column:ip_address"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+type RequestImpersonationAudit struct {
+	Actor     string `query:"actor"`
+	Username  string `query:"username"`
+	SessionID string `query:"session_id"`
+	From      string `query:"from"`
+	To        string `query:"to"`
+	Page      int    `query:"page"`
+	Limit     int    `query:"limit"`
+}
+
+type ImpersonationAuditFilter struct {
+	Actor     string
+	Username  string
+	SessionID string
+	From      *time.Time
+	To        *time.Time
+	Offset    int
+	Limit     int
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+const (
+	InstitutionStatusActive   = "active"
+	InstitutionStatusInactive = "inactive"
+)
+
+// Institution is a tenant. Users, face datasets and trained models belong to one, and its
+// users cannot log in while it is inactive. StorageBucket is where its face datasets are
+// stored.
+type Institution struct {
+	ID            string    `json:"id" gorm:"column:id"`
+	Name          string    `json:"name" gorm:"column:name"`
+	Code          string    `json:"code" gorm:"column:code"`
+	Timezone      string    `json:"timezone" gorm:"column:timezone"`
+	Status        string    `json:"status" gorm:"column:status"`
+	ContactName   string    `json:"contact_name" gorm:"column:contact_name"`
+	ContactEmail  string    `json:"contact_email" gorm:"column:contact_email"`
+	ContactPhone  string    `json:"contact_phone" gorm:"column:contact_phone"`
+	StorageBucket string    `json:"storage_bucket" gorm:"column:storage_bucket"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+	CreatedBy     string    `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy     string    `json:"updated_by" gorm:"column:updated_by"`
+}
+
+type RequestInstitution struct {
+	ID            string `json:"id"`
+	Name          string `json:"name" validate:"required"`
+	Code          string `json:"code" validate:"required"`
+	Timezone      string `json:"timezone"`
+	Status        string `json:"status"`
+	ContactName   string `json:"contact_name"`
+	ContactEmail  string `json:"contact_email"`
+	ContactPhone  string `json:"contact_phone"`
+	StorageBucket string `json:"storage_bucket"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+type Invitation struct {
+	ID            string     `json:"id" gorm:"column:id"`
+	TokenHash     string     `json:"-" gorm:"column:token_hash"`
+	Email         string     `json:"email" gorm:"column:email"`
+	RoleID        string     `json:"role_id" gorm:"column:role_id"`
+	InstitutionID string     `json:"institution_id" gorm:"column:institution_id"`
+	ExpiredAt     time.Time  `json:"expired_at" gorm:"column:expired_at"`
+	UsedAt        *time.Time `json:"used_at" gorm:"column:used_at"`
+	UsedBy        string     `json:"used_by" gorm:"column:used_by"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at"`
+	CreatedBy     string     `json:"created_by" gorm:"column:created_by"`
+}
+
+type RequestCreateInvitation struct {
+	Email         string `json:"email" validate:"required"`
+	RoleID        string `json:"role_id" validate:"required"`
+	InstitutionID string `json:"institution_id" validate:"required"`
+	ExpiryHours   int    `json:"expiry_hours"`
+}
+
+type ResponseCreateInvitation struct {
+	Invitation *Invitation `json:"invitation"`
+	Token      string      `json:"token"`
+}
+
+type RequestAcceptInvitation struct {
+	Token     string `json:"token" validate:"required"`
+	Username  string `json:"username" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+	Fullname  string `json:"fullname" validate:"required"`
+	Shortname string `json:"shortname" validate:"required"`
+}
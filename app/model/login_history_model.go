@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+const (
+	LoginOutcomeSuccess     = "success"
+	LoginOutcomeFailure     = "failure"
+	LoginOutcomeLocked      = "locked"
+	LoginOutcomeMfaRequired = "mfa_required"
+)
+
+// LoginHistory is one login attempt. SessionID is only set for successful logins and
+// InstitutionID is empty for usernames that do not exist.
+type LoginHistory struct {
+	ID            int64     `json:"id" gorm:"column:id"`
+	Username      string    `json:"username" gorm:"column:username"`
+	InstitutionID string    `json:"institution_id" gorm:"column:institution_id"`
+	SessionID     string    `json:"session_id" gorm:"column:session_id"`
+	Method        string    `json:"method" gorm:"column:method"`
+	Outcome       string    `json:"outcome" gorm:"column:outcome"`
+	Reason        string    `json:"reason" gorm:"column:reason"`
+	IPAddress     string    `json:"ip_address" gorm:"column:ip_address"`
+	UserAgent     string    `json:"user_agent" gorm:"column:user_agent"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// RequestLoginHistory filters login history, From and To accept a date or an RFC 3339 time.
+type RequestLoginHistory struct {
+	Username      string `query:"username"`
+	InstitutionID string `query:"institution_id"`
+	Outcome       string `query:"outcome"`
+	From          string `query:"from"`
+	To            string `query:"to"`
+	Page          int    `query:"page"`
+	Limit         int    `query:"limit"`
+}
+
+type LoginHistoryFilter struct {
+	Username      string
+	InstitutionID string
+	Outcome       string
+	From          *time.Time
+	To            *time.Time
+	Offset        int
+	Limit         int
+}
+
+type ResponsePagination struct {
+	Items interface{} `json:"items"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+	Total int64       `json:"total"`
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+const (
+	AuthMethodPassword = "pwd"
+	AuthMethodOTP      = "otp"
+)
+
+// HasMultiFactor reports whether the authentication methods of a session include a second
+// factor, either a TOTP code or a user verified passkey.
+func HasMultiFactor(amr []string) bool {
+	for _, method := range amr {
+		if method == AuthMethodOTP || method == AuthMethodPasskey {
+			return true
+		}
+	}
+	return false
+}
+
+type UserMfa struct {
+	Username  string     `json:"username" gorm:"column:username"`
+	Secret    string     `json:"-" gorm:"column:secret"`
+	IsEnabled bool       `json:"is_enabled" gorm:"column:is_enabled"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at"`
+	EnabledAt *time.Time `json:"enabled_at" gorm:"column:enabled_at"`
+}
+
+type ResponseMfaEnroll struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RequestMfaCode struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type ResponseMfaRecoveryCode struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RequestLoginMfa struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+	IPAddress      string `json:"-"`
+	UserAgent      string `json:"-"`
+}
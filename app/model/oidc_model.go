@@ -0,0 +1,52 @@
+package model
+
+const AuthMethodOIDC = "oidc"
+
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type OIDCTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// OIDCState is kept in Redis between the redirect to the identity provider and the callback.
+type OIDCState struct {
+	InstitutionID string `json:"institution_id"`
+	CodeVerifier  string `json:"code_verifier"`
+	Nonce         string `json:"nonce"`
+}
+
+// OIDCIdentity is the verified content of an ID token.
+type OIDCIdentity struct {
+	Subject       string
+	Username      string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+	Amr           []string
+}
+
+type UserIdentity struct {
+	Provider string `json:"provider" gorm:"column:provider"`
+	Subject  string `json:"subject" gorm:"column:subject"`
+	Username string `json:"username" gorm:"column:username"`
+}
+
+type ResponseOIDCLogin struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+type RequestOIDCCallback struct {
+	State     string `json:"state" validate:"required"`
+	Code      string `json:"code" validate:"required"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
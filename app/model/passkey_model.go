@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuthMethodPasskey marks a WebAuthn assertion. Passkeys are registered and asserted with
+// user verification required, so they count as multi-factor on their own.
+const AuthMethodPasskey = "hwk"
+
+// Passkey is a WebAuthn credential registered by a user. CredentialID is the base64url
+// encoded credential id chosen by the authenticator, ID is ours.
+type Passkey struct {
+	ID              string     `json:"id" gorm:"column:id"`
+	CredentialID    string     `json:"credential_id" gorm:"column:credential_id"`
+	Username        string     `json:"username" gorm:"column:username"`
+	Name            string     `json:"name" gorm:"column:name"`
+	PublicKey       []byte     `json:"-" gorm:"column:public_key"`
+	AttestationType string     `json:"-" gorm:"column:attestation_type"`
+	Transports      string     `json:"transports" gorm:"column:transports"`
+	AAGUID          string     `json:"aaguid" gorm:"column:aaguid"`
+	SignCount       uint32     `json:"-" gorm:"column:sign_count"`
+	BackupEligible  bool       `json:"backup_eligible" gorm:"column:backup_eligible"`
+	BackupState     bool       `json:"backup_state" gorm:"column:backup_state"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+}
+
+// RequestPasskeyRegister finishes a registration, Credential is the PublicKeyCredential
+// returned by navigator.credentials.create() as JSON.
+type RequestPasskeyRegister struct {
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+type ResponsePasskeyLogin struct {
+	ChallengeToken string      `json:"challenge_token"`
+	Options        interface{} `json:"options"`
+}
+
+// RequestLoginPasskey finishes a login, Credential is the PublicKeyCredential returned by
+// navigator.credentials.get() as JSON.
+type RequestLoginPasskey struct {
+	ChallengeToken string          `json:"challenge_token" validate:"required"`
+	Credential     json.RawMessage `json:"credential" validate:"required"`
+	IPAddress      string          `json:"-"`
+	UserAgent      string          `json:"-"`
+}
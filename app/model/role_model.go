@@ -17,9 +17,15 @@ type MenuRoleMapping struct {
 }
 
 type Menu struct {
-	Id        string    `gorm:"column:id" json:"id"`
-	MenuName  string    `gorm:"column:menu_name" json:"menu_name"`
-	MenuRoute string    `gorm:"column:menu_route" json:"menu_route"`
+	Id        string `gorm:"column:id" json:"id"`
+	MenuName  string `gorm:"column:menu_name" json:"menu_name"`
+	MenuRoute string `gorm:"column:menu_route" json:"menu_route"`
+	ParentID  string `gorm:"column:parent_id" json:"parent_id"`
+	SortOrder int    `gorm:"column:sort_order" json:"sort_order"`
+	Icon      string `gorm:"column:icon" json:"icon"`
+	// IsHidden keeps the menu, and the menus under it, out of the navigation tree. Its
+	// routes are still authorized through the menu mappings.
+	IsHidden  bool      `gorm:"column:is_hidden" json:"is_hidden"`
 	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
 	CreatedBy string    `gorm:"column:created_by" json:"created_by"`
@@ -27,12 +33,99 @@ type Menu struct {
 }
 
 type Role struct {
-	Id        string    `gorm:"column:id" json:"id"`
-	RoleName  string    `gorm:"column:role_name" json:"role_name"`
-	RoleDesc  string    `gorm:"column:role_desc" json:"role_desc"`
+	Id       string `gorm:"column:id" json:"id"`
+	RoleName string `gorm:"column:role_name" json:"role_name"`
+	RoleDesc string `gorm:"column:role_desc" json:"role_desc"`
+	// ParentID is the role whose permissions this role inherits, if any.
+	ParentID  string    `gorm:"column:parent_id" json:"parent_id"`
 	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
 	CreatedBy string    `gorm:"column:created_by" json:"created_by"`
 	UpdatedBy string    `gorm:"column:updated_by" json:"updated_by"`
 	IsActive  bool      `gorm:"column:is_active" json:"is_active"`
+
+	MfaRequired bool `gorm:"column:mfa_required" json:"mfa_required"`
+	// IsSuperAdmin lets users of the role act across institutions.
+	IsSuperAdmin bool `gorm:"column:is_super_admin" json:"is_super_admin"`
+}
+
+// EffectivePermission is what a role may do on a menu once its ancestors' permissions are
+// added to its own. AccessMethod is the union of the methods of all sources.
+type EffectivePermission struct {
+	MenuID       string              `json:"menu_id"`
+	MenuName     string              `json:"menu_name"`
+	MenuRoute    string              `json:"menu_route"`
+	AccessMethod string              `json:"access_method"`
+	Sources      []*PermissionSource `json:"sources"`
+}
+
+// PermissionSource is one mapping contributing to an effective permission, Inherited when
+// it belongs to an ancestor rather than the role itself.
+type PermissionSource struct {
+	MappingID    string `json:"mapping_id"`
+	RoleID       string `json:"role_id"`
+	RoleName     string `json:"role_name"`
+	AccessMethod string `json:"access_method"`
+	Inherited    bool   `json:"inherited"`
+}
+
+// UserRole grants a role to a user on top of the primary role in users.role_id. A grant with
+// an ExpiresAt stops applying at that time.
+type UserRole struct {
+	ID        string     `gorm:"column:id" json:"id"`
+	Username  string     `gorm:"column:username" json:"username"`
+	RoleID    string     `gorm:"column:role_id" json:"role_id"`
+	RoleName  string     `gorm:"column:role_name" json:"role_name"`
+	ExpiresAt *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+	CreatedBy string     `gorm:"column:created_by" json:"created_by"`
+}
+
+// IsActiveAt reports whether the grant still applies at t.
+func (g *UserRole) IsActiveAt(t time.Time) bool {
+	return g.ExpiresAt == nil || g.ExpiresAt.After(t)
+}
+
+// MenuTree is a menu in the caller's navigation, with the methods the caller may use on it.
+// A menu the caller is not mapped to is only present to hold the menus under it.
+type MenuTree struct {
+	Id           string      `json:"id"`
+	MenuName     string      `json:"menu_name"`
+	MenuRoute    string      `json:"menu_route"`
+	Icon         string      `json:"icon"`
+	SortOrder    int         `json:"sort_order"`
+	AccessMethod string      `json:"access_method"`
+	Children     []*MenuTree `json:"children"`
+}
+
+// RequestRolePermission is the full set of menus a role is mapped to and the methods it may
+// use on each. Menus left out, or given no methods, lose their mapping.
+type RequestRolePermission struct {
+	RoleID      string                   `json:"-"`
+	DryRun      bool                     `json:"-"`
+	Permissions []*RequestMenuPermission `json:"permissions"`
+}
+
+type RequestMenuPermission struct {
+	MenuID       string `json:"menu_id"`
+	AccessMethod string `json:"access_method"`
+}
+
+// ResponsePermissionDiff lists the mapping changes made, or with DryRun that would be made,
+// to bring a role in line with a RequestRolePermission.
+type ResponsePermissionDiff struct {
+	RoleID    string              `json:"role_id"`
+	DryRun    bool                `json:"dry_run"`
+	Created   []*MenuRoleMapping  `json:"created"`
+	Updated   []*PermissionChange `json:"updated"`
+	Deleted   []*MenuRoleMapping  `json:"deleted"`
+	Unchanged int                 `json:"unchanged"`
+}
+
+type PermissionChange struct {
+	Id         string `json:"id"`
+	MenuID     string `json:"menu_id"`
+	MenuName   string `json:"menu_name"`
+	FromMethod string `json:"from_access_method"`
+	ToMethod   string `json:"to_access_method"`
 }
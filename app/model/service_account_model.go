@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+const AuthMethodApiKey = "apikey"
+
+// ServiceAccount is a non-human caller. Its role decides which menus and methods its API
+// keys may use, the same way a role does for users.
+type ServiceAccount struct {
+	ID            string    `json:"id" gorm:"column:id"`
+	Name          string    `json:"name" gorm:"column:name"`
+	Description   string    `json:"description" gorm:"column:description"`
+	RoleID        string    `json:"role_id" gorm:"column:role_id"`
+	InstitutionID string    `json:"institution_id" gorm:"column:institution_id"`
+	IsActive      bool      `json:"is_active" gorm:"column:is_active"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+	CreatedBy     string    `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy     string    `json:"updated_by" gorm:"column:updated_by"`
+}
+
+type ApiKey struct {
+	ID               string     `json:"id" gorm:"column:id"`
+	ServiceAccountID string     `json:"service_account_id" gorm:"column:service_account_id"`
+	KeyPrefix        string     `json:"key_prefix" gorm:"column:key_prefix"`
+	KeyHash          string     `json:"-" gorm:"column:key_hash"`
+	ExpiredAt        *time.Time `json:"expired_at" gorm:"column:expired_at"`
+	LastUsedAt       *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	RevokedBy        string     `json:"revoked_by" gorm:"column:revoked_by"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"column:created_at"`
+	CreatedBy        string     `json:"created_by" gorm:"column:created_by"`
+}
+
+// ApiKeyIdentity is an API key joined with the service account it belongs to.
+type ApiKeyIdentity struct {
+	ID               string     `json:"id" gorm:"column:id"`
+	ServiceAccountID string     `json:"service_account_id" gorm:"column:service_account_id"`
+	Name             string     `json:"name" gorm:"column:name"`
+	RoleID           string     `json:"role_id" gorm:"column:role_id"`
+	InstitutionID    string     `json:"institution_id" gorm:"column:institution_id"`
+	IsActive         bool       `json:"is_active" gorm:"column:is_active"`
+	ExpiredAt        *time.Time `json:"expired_at" gorm:"column:expired_at"`
+	RevokedAt        *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+}
+
+type RequestServiceAccount struct {
+	ID            string `json:"id"`
+	Name          string `json:"name" validate:"required"`
+	Description   string `json:"description"`
+	RoleID        string `json:"role_id" validate:"required"`
+	InstitutionID string `json:"institution_id" validate:"required"`
+	IsActive      bool   `json:"is_active"`
+}
+
+type RequestCreateApiKey struct {
+	ServiceAccountID string `json:"service_account_id" validate:"required"`
+	ExpiryDays       int    `json:"expiry_days"`
+}
+
+type ResponseCreateApiKey struct {
+	ApiKey *ApiKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
@@ -1,43 +1,142 @@
 package model
 
 import (
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// JwtCustomClaims carries only the identity and role of the caller, permissions are
+// resolved per request. The token id is the registered "jti" claim and the login
+// session it belongs to is "sid", both of which can be revoked.
 type JwtCustomClaims struct {
-	Name        string            `json:"name"`
-	Role        string            `json:"role"`
-	MenuMapping map[string]string `json:"menu_mapping"`
+	Name        string      `json:"name"`
+	Role        string      `json:"role"`
+	Institution string      `json:"inst,omitempty"`
+	SessionID   string      `json:"sid"`
+	Amr         []string    `json:"amr,omitempty"`
+	Act         *ActorClaim `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type JwtRefreshClaims struct {
+	Name      string   `json:"name"`
+	SessionID string   `json:"sid"`
+	Amr       []string `json:"amr,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type MetadataUser struct {
-	Username string `json:"username"`
-	RoleID   string `json:"role_id"`
+	Username      string   `json:"username"`
+	RoleID        string   `json:"role_id"`
+	InstitutionID string   `json:"institution_id"`
+	TokenID       string   `json:"token_id"`
+	SessionID     string   `json:"session_id"`
+	ExpiredAt     int64    `json:"expired_at"`
+	Amr           []string `json:"amr,omitempty"`
+	// Actor is the administrator impersonating Username, empty for a regular session.
+	Actor          string `json:"actor,omitempty"`
+	ActorSessionID string `json:"actor_session_id,omitempty"`
+	ReadOnly       bool   `json:"read_only,omitempty"`
 }
 
-type User struct {
-	Username      string `json:"username" gorm:"column:username" validate:"required"`
-	Email         string `json:"email" gorm:"column:email" validate:"required"`
-	Password      string `json:"password" gorm:"column:password" validate:"required"`
-	Fullname      string `json:"fullname" gorm:"column:fullname" validate:"required"`
-	Shortname     string `json:"shortname" gorm:"column:shortname" validate:"required"`
-	RoleID        string `json:"role_id" gorm:"column:role_id" validate:"required"`
-	InstitutionID string `json:"institution_id" gorm:"column:institution_id" validate:"required"`
+type UserSession struct {
+	SessionID       string    `json:"session_id"`
+	Username        string    `json:"username"`
+	Method          string    `json:"method,omitempty"`
+	IPAddress       string    `json:"ip_address,omitempty"`
+	UserAgent       string    `json:"user_agent,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	AccessExpiredAt time.Time `json:"access_expired_at"`
+	Current         bool      `json:"current"`
+}
+
+// UserDetail is the projection of a user that is safe to return and log, it never
+// carries credentials.
+type UserDetail struct {
+	Username      string `json:"username" gorm:"column:username"`
+	Email         string `json:"email" gorm:"column:email"`
+	Fullname      string `json:"fullname" gorm:"column:fullname"`
+	Shortname     string `json:"shortname" gorm:"column:shortname"`
+	RoleID        string `json:"role_id" gorm:"column:role_id"`
+	InstitutionID string `json:"institution_id" gorm:"column:institution_id"`
+	IsActive      bool   `json:"is_active" gorm:"column:is_active"`
+	AuthSource    string `json:"auth_source" gorm:"column:auth_source"`
 	CreatedAt     string `json:"created_at" gorm:"column:created_at"`
 }
 
+// User is the full users row including the password hash, which is never serialized.
+type User struct {
+	UserDetail
+	Password string `json:"-" gorm:"column:password"`
+}
+
+type RequestRegister struct {
+	Username  string `json:"username" validate:"required"`
+	Email     string `json:"email" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+	Fullname  string `json:"fullname" validate:"required"`
+	Shortname string `json:"shortname" validate:"required"`
+}
+
+type RequestUpdateUser struct {
+	Username      string    `json:"username" validate:"required"`
+	Email         string    `json:"email" validate:"required"`
+	Fullname      string    `json:"fullname" validate:"required"`
+	Shortname     string    `json:"shortname" validate:"required"`
+	RoleID        string    `json:"role_id" validate:"required"`
+	InstitutionID string    `json:"institution_id" validate:"required"`
+	UpdatedAt     time.Time `json:"-"`
+	UpdatedBy     string    `json:"-"`
+}
+
+type RequestUpdateProfile struct {
+	Email     string    `json:"email" validate:"required"`
+	Fullname  string    `json:"fullname" validate:"required"`
+	Shortname string    `json:"shortname" validate:"required"`
+	UpdatedAt time.Time `json:"-"`
+	UpdatedBy string    `json:"-"`
+}
+
+type RequestChangePassword struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type RequestUserStatus struct {
+	Username  string    `json:"username" validate:"required"`
+	IsActive  bool      `json:"is_active"`
+	UpdatedAt time.Time `json:"-"`
+	UpdatedBy string    `json:"-"`
+}
+
+type ResponseResetPassword struct {
+	Username          string `json:"username"`
+	TemporaryPassword string `json:"temporary_password"`
+}
+
 type RequestLogin struct {
-	Username string `json:"username" gorm:"column:username" validate:"required"`
-	Password string `json:"password" gorm:"column:password" validate:"required"`
+	Username  string `json:"username" gorm:"column:username" validate:"required"`
+	Password  string `json:"password" gorm:"column:password" validate:"required"`
+	IPAddress string `json:"-" gorm:"-"`
+	UserAgent string `json:"-" gorm:"-"`
+}
+
+type RequestRefreshToken struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 type ResponseLogin struct {
-	Username      string             `json:"username" gorm:"type:varchar(200);"`
-	Fullname      string             `json:"fullname" gorm:"type:varchar(200);"`
-	Shortname     string             `json:"shortname" gorm:"type:varchar(200);"`
-	Role          string             `json:"role" gorm:"type:varchar(200);"`
-	Token         string             `json:"token" gorm:"type:varchar(200);"`
-	InstitutionID string             `json:"institution_id" gorm:"type:varchar(200);"`
-	MenuMapping   []*MenuRoleMapping `json:"menu_mapping" gorm:"-"`
+	Username              string             `json:"username" gorm:"type:varchar(200);"`
+	Fullname              string             `json:"fullname" gorm:"type:varchar(200);"`
+	Shortname             string             `json:"shortname" gorm:"type:varchar(200);"`
+	Role                  string             `json:"role" gorm:"type:varchar(200);"`
+	Roles                 []string           `json:"roles" gorm:"-"`
+	Token                 string             `json:"token" gorm:"type:varchar(200);"`
+	RefreshToken          string             `json:"refresh_token" gorm:"type:varchar(200);"`
+	InstitutionID         string             `json:"institution_id" gorm:"type:varchar(200);"`
+	MenuMapping           []*MenuRoleMapping `json:"menu_mapping" gorm:"-"`
+	MfaRequired           bool               `json:"mfa_required" gorm:"-"`
+	MfaEnrollmentRequired bool               `json:"mfa_enrollment_required" gorm:"-"`
+	ChallengeToken        string             `json:"challenge_token,omitempty" gorm:"-"`
 }
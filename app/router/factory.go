@@ -4,32 +4,48 @@ import (
 	"face-recognition-svc/app/client"
 	"face-recognition-svc/app/config"
 	"face-recognition-svc/app/controller"
+	"face-recognition-svc/app/model"
 	"face-recognition-svc/app/service"
+	"face-recognition-svc/app/utils"
 
 	"github.com/aws/aws-sdk-go/service/s3"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 
 	"gorm.io/gorm"
 )
 
 type ServiceFactory struct {
-	user  service.InterfaceUserService
-	role  service.InterfaceRoleService
-	param service.InterfaceParamService
+	user           service.InterfaceUserService
+	role           service.InterfaceRoleService
+	param          service.InterfaceParamService
+	serviceAccount service.InterfaceServiceAccountService
+	institution    service.InterfaceInstitutionService
 }
 
 type ControllerFactory struct {
-	user  controller.InterfaceUserController
-	role  controller.InterfaceRoleController
-	param controller.InterfaceParamController
+	user           controller.InterfaceUserController
+	role           controller.InterfaceRoleController
+	param          controller.InterfaceParamController
+	serviceAccount controller.InterfaceServiceAccountController
+	institution    controller.InterfaceInstitutionController
 }
 
 type ClientFactory struct {
-	user    client.InterfaceUserClient
-	storage client.InterfaceStorageClient
-	role    client.InterfaceRoleClient
-	param   client.InterfaceParamClient
+	user           client.InterfaceUserClient
+	invitation     client.InterfaceInvitationClient
+	mfa            client.InterfaceMfaClient
+	oidc           client.InterfaceOIDCClient
+	passkey        client.InterfacePasskeyClient
+	loginHistory   client.InterfaceLoginHistoryClient
+	impersonation  client.InterfaceImpersonationClient
+	storage        client.InterfaceStorageClient
+	role           client.InterfaceRoleClient
+	userRole       client.InterfaceUserRoleClient
+	param          client.InterfaceParamClient
+	serviceAccount client.InterfaceServiceAccountClient
+	institution    client.InterfaceInstitutionClient
 }
 
 type Factory struct {
@@ -41,21 +57,47 @@ type Factory struct {
 var factory *Factory
 
 func InitFactory(cfg *config.Config, db *gorm.DB, s3 *s3.S3, redis *redis.Client, mq *amqp.Channel) {
+	authenticators := map[string]client.InterfaceAuthenticator{}
+	if cfg.Auth.LDAP.Enabled {
+		authenticators[model.AuthSourceLDAP] = client.NewLdapClient(cfg)
+	}
+
+	passkeyClient, err := client.NewPasskeyClient(db, cfg)
+	if err != nil {
+		logrus.Fatalf("Failed to configure WebAuthn: %v", err)
+	}
+
 	client := ClientFactory{
-		user:    client.NewUserClient(db, cfg),
-		storage: client.NewStorageClient(s3, db),
-		role:    client.NewRoleClient(db),
-		param:   client.NewParamClient(db),
+		user:           client.NewUserClient(db, cfg),
+		invitation:     client.NewInvitationClient(db),
+		mfa:            client.NewMfaClient(db),
+		oidc:           client.NewOIDCClient(cfg),
+		passkey:        passkeyClient,
+		loginHistory:   client.NewLoginHistoryClient(db),
+		impersonation:  client.NewImpersonationClient(db),
+		storage:        client.NewStorageClient(s3, db),
+		role:           client.NewRoleClient(db),
+		userRole:       client.NewUserRoleClient(db),
+		param:          client.NewParamClient(db),
+		serviceAccount: client.NewServiceAccountClient(db),
+		institution:    client.NewInstitutionClient(db),
 	}
+	roleController := controller.NewRoleController(redis, client.role, client.userRole)
+	paramController := controller.NewParamController(redis, client.param)
+	userController := controller.NewUserController(redis, client.user, client.invitation, client.mfa, client.oidc, client.passkey, client.loginHistory, client.impersonation, client.institution, authenticators, roleController, paramController)
 	controller := ControllerFactory{
-		user:  controller.NewUserController(client.user, client.role),
-		role:  controller.NewRoleController(client.role),
-		param: controller.NewParamController(redis, client.param),
+		user:           userController,
+		role:           roleController,
+		param:          paramController,
+		serviceAccount: controller.NewServiceAccountController(redis, client.serviceAccount, roleController),
+		institution:    controller.NewInstitutionController(client.institution, userController),
 	}
 	service := ServiceFactory{
-		user:  service.NewUserService(controller.user),
-		role:  service.NewRoleService(controller.role),
-		param: service.NewParamService(controller.param),
+		user:           service.NewUserService(controller.user),
+		role:           service.NewRoleService(controller.role),
+		param:          service.NewParamService(controller.param),
+		serviceAccount: service.NewServiceAccountService(controller.serviceAccount),
+		institution:    service.NewInstitutionService(controller.institution),
 	}
 	factory = &Factory{
 		Service:    service,
@@ -63,3 +105,23 @@ func InitFactory(cfg *config.Config, db *gorm.DB, s3 *s3.S3, redis *redis.Client
 		Client:     client,
 	}
 }
+
+// Authorizer returns the permission check used by the service route authorization middleware.
+func Authorizer() utils.Authorizer {
+	return factory.Controller.role
+}
+
+// TenantResolver returns the check deciding which callers may act across institutions.
+func TenantResolver() utils.TenantResolver {
+	return factory.Controller.role
+}
+
+// ImpersonationAuditor returns the recorder of requests made with impersonation tokens.
+func ImpersonationAuditor() utils.ImpersonationAuditor {
+	return factory.Controller.user
+}
+
+// ApiKeyAuthenticator returns the API key check used in front of the JWT middleware.
+func ApiKeyAuthenticator() utils.ApiKeyAuthenticator {
+	return factory.Controller.serviceAccount
+}
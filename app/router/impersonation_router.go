@@ -0,0 +1,11 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitImpersonationRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.user
+
+	route.POST("", service.Impersonate)
+	route.GET("/audit", service.GetImpersonationAudit)
+}
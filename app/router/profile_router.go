@@ -0,0 +1,26 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+// InitProfileRoute registers the self-service routes of the logged-in user, they only
+// ever act on the username carried by the caller's own token.
+func InitProfileRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.user
+
+	route.GET("", service.GetProfile)
+	route.PUT("", service.UpdateProfile)
+	route.GET("/menu", service.GetMyMenu)
+	route.PUT("/password", service.ChangePassword)
+	route.POST("/2fa/enroll", service.EnrollMfa)
+	route.POST("/2fa/activate", service.ActivateMfa)
+	route.POST("/2fa/recovery-codes", service.RegenerateRecoveryCode)
+	route.POST("/2fa/disable", service.DisableMfa)
+	route.GET("/passkey", service.GetPasskey)
+	route.POST("/passkey/register/begin", service.BeginPasskeyRegistration)
+	route.POST("/passkey/register/finish", service.FinishPasskeyRegistration)
+	route.DELETE("/passkey/:id", service.DeletePasskey)
+	route.GET("/sessions", service.GetMySession)
+	route.DELETE("/sessions/:id", service.RevokeMySession)
+	route.GET("/login-history", service.GetMyLoginHistory)
+}
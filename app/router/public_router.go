@@ -2,6 +2,7 @@ package router
 
 import (
 	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -19,6 +20,19 @@ func InitPublicRoute(prefix string, e *echo.Group) {
 		})
 	})
 
+	route.GET("/.well-known/jwks.json", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(http.StatusOK, utils.Keys.JWKS())
+	})
+
 	route.POST("/register", service.CreateNewUser)
+	route.POST("/register/invitation", service.AcceptInvitation)
 	route.POST("/login", service.Login)
+	route.POST("/login/2fa", service.LoginMfa)
+	route.POST("/login/passkey/begin", service.BeginPasskeyLogin)
+	route.POST("/login/passkey/finish", service.LoginPasskey)
+	route.POST("/refresh", service.RefreshToken)
+
+	route.GET("/oidc/:id/login", service.StartOIDCLogin)
+	route.POST("/oidc/callback", service.OIDCCallback)
 }
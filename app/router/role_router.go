@@ -7,10 +7,21 @@ func InitRoleRoute(prefix string, e *echo.Group) {
 	service := factory.Service.role
 
 	route.GET("", service.GetAllRole)
+	route.POST("/create", service.CreateNewRole)
+	route.PUT("", service.UpdateRole)
+	route.PUT("/status", service.UpdateRoleStatus)
+	route.DELETE("/:id", service.DeleteRole)
+	route.GET("/:id/permission", service.GetEffectivePermission)
+	route.PUT("/:id/permission", service.SetRolePermission)
+
+	route.GET("/user/:id", service.GetUserRole)
+	route.POST("/user", service.GrantUserRole)
+	route.DELETE("/user/:id/:grant", service.RevokeUserRole)
 
 	route.GET("/mapping", service.GetAllRoleMapping)
-	route.POST("/create", service.CreateNewRole)
 	route.POST("/mapping/create", service.CreateNewRoleMapping)
+	route.PUT("/mapping", service.UpdateRoleMapping)
+	route.DELETE("/mapping/:id", service.DeleteRoleMapping)
 
 	route.GET("/menu", service.GetAllMenu)
 	route.PUT("/menu", service.UpdateMenu)
@@ -0,0 +1,17 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitServiceAccountRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.serviceAccount
+
+	route.GET("", service.GetAllServiceAccount)
+	route.POST("/create", service.CreateServiceAccount)
+	route.PUT("", service.UpdateServiceAccount)
+	route.DELETE("/:id", service.DeleteServiceAccount)
+
+	route.GET("/key/:id", service.GetApiKeyByServiceAccount)
+	route.POST("/key/create", service.CreateApiKey)
+	route.DELETE("/key/:id", service.RevokeApiKey)
+}
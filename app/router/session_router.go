@@ -0,0 +1,13 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+// InitSessionRoute registers routes every authenticated user may call on their own
+// session, regardless of the menus mapped to their role.
+func InitSessionRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.user
+
+	route.POST("/logout", service.Logout)
+	route.POST("/logout/all", service.LogoutAll)
+}
@@ -8,6 +8,20 @@ func InitUserRoute(prefix string, e *echo.Group) {
 
 	route.GET("", service.GetAllUser)
 	route.GET("/detail/:id", service.GetUserDetail)
+	route.PUT("", service.UpdateUser)
+	route.PUT("/status", service.UpdateUserStatus)
+	route.DELETE("/:id", service.DeleteUser)
+	route.POST("/reset-password/:id", service.ResetPassword)
+	route.DELETE("/lock/:id", service.UnlockLogin)
+	route.DELETE("/2fa/:id", service.ResetUserMfa)
 
 	route.GET("/institutions", service.GetInstitutionList)
+
+	route.GET("/sessions/:id", service.GetUserSession)
+	route.DELETE("/sessions/:id", service.ForceLogout)
+	route.GET("/login-history", service.GetLoginHistory)
+
+	route.GET("/invitation", service.GetAllInvitation)
+	route.POST("/invitation", service.CreateInvitation)
+	route.DELETE("/invitation/:id", service.DeleteInvitation)
 }
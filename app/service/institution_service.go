@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/app/controller"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceInstitutionService interface {
+	GetAllInstitution(e echo.Context) error
+	GetInstitutionByID(e echo.Context) error
+	CreateInstitution(e echo.Context) error
+	UpdateInstitution(e echo.Context) error
+	DeleteInstitution(e echo.Context) error
+}
+
+type InstitutionService struct {
+	uc controller.InterfaceInstitutionController
+}
+
+func NewInstitutionService(uc controller.InterfaceInstitutionController) InterfaceInstitutionService {
+	return &InstitutionService{
+		uc: uc,
+	}
+}
+
+func (s *InstitutionService) GetAllInstitution(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetAllInstitution")
+	defer span.Finish()
+
+	response, err := s.uc.GetAllInstitution(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get All Institution",
+		Data:    response,
+	})
+}
+
+func (s *InstitutionService) GetInstitutionByID(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetInstitutionByID")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	response, err := s.uc.GetInstitutionByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Institution",
+		Data:    response,
+	})
+}
+
+func (s *InstitutionService) CreateInstitution(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CreateInstitution")
+	defer span.Finish()
+
+	var request *model.RequestInstitution
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Name == "" || request.Code == "" {
+		utils.LogEventError(span, errors.New("name and code shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("name and code shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	response, err := s.uc.CreateInstitution(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Institution")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Create Institution",
+		Data:    response,
+	})
+}
+
+func (s *InstitutionService) UpdateInstitution(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateInstitution")
+	defer span.Finish()
+
+	var request *model.RequestInstitution
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.ID == "" || request.Name == "" || request.Code == "" {
+		utils.LogEventError(span, errors.New("id, name and code shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id, name and code shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.UpdateInstitution(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Institution")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update Institution",
+		Data:    nil,
+	})
+}
+
+func (s *InstitutionService) DeleteInstitution(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteInstitution")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.DeleteInstitution(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Institution")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Institution",
+		Data:    nil,
+	})
+}
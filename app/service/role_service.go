@@ -6,6 +6,7 @@ import (
 	"face-recognition-svc/app/model"
 	"face-recognition-svc/app/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
@@ -13,6 +14,8 @@ import (
 type InterfaceRoleService interface {
 	CreateNewRoleMapping(e echo.Context) error
 	GetAllRoleMapping(e echo.Context) error
+	UpdateRoleMapping(e echo.Context) error
+	DeleteRoleMapping(e echo.Context) error
 
 	GetAllMenu(e echo.Context) error
 	CreateNewMenu(e echo.Context) error
@@ -21,6 +24,15 @@ type InterfaceRoleService interface {
 
 	GetAllRole(e echo.Context) error
 	CreateNewRole(e echo.Context) error
+	UpdateRole(e echo.Context) error
+	UpdateRoleStatus(e echo.Context) error
+	DeleteRole(e echo.Context) error
+	GetEffectivePermission(e echo.Context) error
+	SetRolePermission(e echo.Context) error
+
+	GetUserRole(e echo.Context) error
+	GrantUserRole(e echo.Context) error
+	RevokeUserRole(e echo.Context) error
 }
 
 type RoleService struct {
@@ -171,6 +183,314 @@ func (s *RoleService) CreateNewRole(e echo.Context) error {
 	})
 }
 
+func (s *RoleService) UpdateRole(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateRole")
+	defer span.Finish()
+
+	var request *model.Role
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.UpdateRole(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Role")
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update Role",
+		Data:    nil,
+	})
+}
+
+func (s *RoleService) UpdateRoleStatus(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateRoleStatus")
+	defer span.Finish()
+
+	var request *model.Role
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.UpdateRoleStatus(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Role Status")
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update Role Status",
+		Data:    nil,
+	})
+}
+
+func (s *RoleService) DeleteRole(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteRole")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.DeleteRole(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Role")
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Role",
+		Data:    nil,
+	})
+}
+
+func (s *RoleService) GetEffectivePermission(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetEffectivePermission")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	data, err := s.uc.GetEffectivePermission(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", data)
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Effective Permission",
+		Data:    data,
+	})
+}
+
+// SetRolePermission replaces the role's menu mappings with the matrix in the body. With
+// ?dry_run=true the changes are only returned.
+func (s *RoleService) SetRolePermission(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "SetRolePermission")
+	defer span.Finish()
+
+	var request *model.RequestRolePermission
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	request.RoleID = e.Param("id")
+	if request.RoleID == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	if value := e.QueryParam("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.LogEventError(span, errors.New("dry_run must be true or false"))
+			return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("dry_run must be true or false")), nil)
+		}
+		request.DryRun = dryRun
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	data, err := s.uc.SetRolePermission(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	message := "Success Update Role Permission"
+	if request.DryRun {
+		message = "Success Preview Role Permission"
+	}
+
+	utils.LogEvent(span, "Response", data)
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: message,
+		Data:    data,
+	})
+}
+
+func (s *RoleService) GetUserRole(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetUserRole")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	data, err := s.uc.GetUserRole(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", data)
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get User Role",
+		Data:    data,
+	})
+}
+
+func (s *RoleService) GrantUserRole(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GrantUserRole")
+	defer span.Finish()
+
+	var request *model.UserRole
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Username == "" || request.RoleID == "" {
+		utils.LogEventError(span, errors.New("username and role_id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("username and role_id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.GrantUserRole(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Grant User Role")
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Grant User Role",
+		Data:    request,
+	})
+}
+
+func (s *RoleService) RevokeUserRole(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "RevokeUserRole")
+	defer span.Finish()
+
+	id := e.Param("id")
+	grantID := e.Param("grant")
+	if id == "" || grantID == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	utils.LogEvent(span, "Request", map[string]string{"id": id, "grant": grantID})
+
+	err := s.uc.RevokeUserRole(ctx, id, grantID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Revoke User Role")
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Revoke User Role",
+		Data:    nil,
+	})
+}
+
+func (s *RoleService) UpdateRoleMapping(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateRoleMapping")
+	defer span.Finish()
+
+	var request *model.MenuRoleMapping
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.UpdateRoleMapping(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Role Mapping")
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update Role Mapping",
+		Data:    nil,
+	})
+}
+
+func (s *RoleService) DeleteRoleMapping(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteRoleMapping")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.DeleteRoleMapping(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Role Mapping")
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Role Mapping",
+		Data:    nil,
+	})
+}
+
 func (s *RoleService) UpdateMenu(e echo.Context) error {
 	ctx, span := utils.StartSpan(e, "UpdateRole")
 	defer span.Finish()
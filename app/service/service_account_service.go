@@ -0,0 +1,231 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/app/controller"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceServiceAccountService interface {
+	GetAllServiceAccount(e echo.Context) error
+	CreateServiceAccount(e echo.Context) error
+	UpdateServiceAccount(e echo.Context) error
+	DeleteServiceAccount(e echo.Context) error
+
+	GetApiKeyByServiceAccount(e echo.Context) error
+	CreateApiKey(e echo.Context) error
+	RevokeApiKey(e echo.Context) error
+}
+
+type ServiceAccountService struct {
+	uc controller.InterfaceServiceAccountController
+}
+
+func NewServiceAccountService(uc controller.InterfaceServiceAccountController) InterfaceServiceAccountService {
+	return &ServiceAccountService{
+		uc: uc,
+	}
+}
+
+func (s *ServiceAccountService) GetAllServiceAccount(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetAllServiceAccount")
+	defer span.Finish()
+
+	response, err := s.uc.GetAllServiceAccount(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get All Service Account",
+		Data:    response,
+	})
+}
+
+func (s *ServiceAccountService) CreateServiceAccount(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CreateServiceAccount")
+	defer span.Finish()
+
+	var request *model.RequestServiceAccount
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Name == "" || request.RoleID == "" || request.InstitutionID == "" {
+		utils.LogEventError(span, errors.New("name, role_id and institution_id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("name, role_id and institution_id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	response, err := s.uc.CreateServiceAccount(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Service Account")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Create Service Account",
+		Data:    response,
+	})
+}
+
+func (s *ServiceAccountService) UpdateServiceAccount(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateServiceAccount")
+	defer span.Finish()
+
+	var request *model.RequestServiceAccount
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.ID == "" || request.Name == "" || request.RoleID == "" || request.InstitutionID == "" {
+		utils.LogEventError(span, errors.New("id, name, role_id and institution_id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id, name, role_id and institution_id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.UpdateServiceAccount(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Service Account")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update Service Account",
+		Data:    nil,
+	})
+}
+
+func (s *ServiceAccountService) DeleteServiceAccount(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteServiceAccount")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.DeleteServiceAccount(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Service Account")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Service Account",
+		Data:    nil,
+	})
+}
+
+func (s *ServiceAccountService) GetApiKeyByServiceAccount(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetApiKeyByServiceAccount")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	response, err := s.uc.GetApiKeyByServiceAccount(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Api Key",
+		Data:    response,
+	})
+}
+
+func (s *ServiceAccountService) CreateApiKey(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CreateApiKey")
+	defer span.Finish()
+
+	var request *model.RequestCreateApiKey
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.ServiceAccountID == "" {
+		utils.LogEventError(span, errors.New("service_account_id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("service_account_id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	response, err := s.uc.CreateApiKey(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Api Key")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Create Api Key",
+		Data:    response,
+	})
+}
+
+func (s *ServiceAccountService) RevokeApiKey(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "RevokeApiKey")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.RevokeApiKey(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Revoke Api Key")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Revoke Api Key",
+		Data:    nil,
+	})
+}
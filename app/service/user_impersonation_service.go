@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *UserService) Impersonate(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "Impersonate")
+	defer span.Finish()
+
+	var request *model.RequestImpersonation
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Username == "" {
+		utils.LogEventError(span, errors.New("username shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("username shouldn't be empty")), nil)
+	}
+
+	if request.Reason == "" {
+		utils.LogEventError(span, errors.New("reason shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("reason shouldn't be empty")), nil)
+	}
+
+	request.IPAddress = e.RealIP()
+
+	utils.LogEvent(span, "Request", request.Username)
+
+	response, err := s.uc.Impersonate(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", response.Username)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Impersonate",
+		Data:    response,
+	})
+}
+
+func (s *UserService) GetImpersonationAudit(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetImpersonationAudit")
+	defer span.Finish()
+
+	var request *model.RequestImpersonationAudit
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	response, err := s.uc.GetImpersonationAudit(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Impersonation Audit",
+		Data:    response,
+	})
+}
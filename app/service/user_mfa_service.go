@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *UserService) LoginMfa(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "LoginMfa")
+	defer span.Finish()
+
+	var request *model.RequestLoginMfa
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.ChallengeToken == "" {
+		utils.LogEventError(span, errors.New("challenge_token shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("challenge_token shouldn't be empty")), nil)
+	}
+
+	request.IPAddress = e.RealIP()
+	request.UserAgent = e.Request().UserAgent()
+
+	response, err := s.uc.LoginMfa(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Login",
+		Data:    response,
+	})
+}
+
+func (s *UserService) EnrollMfa(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "EnrollMfa")
+	defer span.Finish()
+
+	response, err := s.uc.EnrollMfa(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Enroll Two-Factor Authentication")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Enroll Two-Factor Authentication",
+		Data:    response,
+	})
+}
+
+func (s *UserService) ActivateMfa(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "ActivateMfa")
+	defer span.Finish()
+
+	var request *model.RequestMfaCode
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Code == "" {
+		utils.LogEventError(span, errors.New("code shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("code shouldn't be empty")), nil)
+	}
+
+	response, err := s.uc.ActivateMfa(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Activate Two-Factor Authentication")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Activate Two-Factor Authentication",
+		Data:    response,
+	})
+}
+
+func (s *UserService) RegenerateRecoveryCode(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "RegenerateRecoveryCode")
+	defer span.Finish()
+
+	var request *model.RequestMfaCode
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Code == "" {
+		utils.LogEventError(span, errors.New("code shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("code shouldn't be empty")), nil)
+	}
+
+	response, err := s.uc.RegenerateRecoveryCode(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Regenerate Recovery Code")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Regenerate Recovery Code",
+		Data:    response,
+	})
+}
+
+func (s *UserService) DisableMfa(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DisableMfa")
+	defer span.Finish()
+
+	var request *model.RequestMfaCode
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	err := s.uc.DisableMfa(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Disable Two-Factor Authentication")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Disable Two-Factor Authentication",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) ResetUserMfa(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "ResetUserMfa")
+	defer span.Finish()
+
+	username := e.Param("id")
+	if username == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", username)
+
+	err := s.uc.ResetUserMfa(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Reset Two-Factor Authentication")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Reset Two-Factor Authentication",
+		Data:    nil,
+	})
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *UserService) StartOIDCLogin(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "StartOIDCLogin")
+	defer span.Finish()
+
+	institutionID := e.Param("id")
+	if institutionID == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	response, err := s.uc.StartOIDCLogin(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Start OIDC Login",
+		Data:    response,
+	})
+}
+
+func (s *UserService) OIDCCallback(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "OIDCCallback")
+	defer span.Finish()
+
+	var request *model.RequestOIDCCallback
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.State == "" || request.Code == "" {
+		utils.LogEventError(span, errors.New("state and code shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("state and code shouldn't be empty")), nil)
+	}
+
+	request.IPAddress = e.RealIP()
+	request.UserAgent = e.Request().UserAgent()
+
+	response, err := s.uc.OIDCCallback(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Login",
+		Data:    response,
+	})
+}
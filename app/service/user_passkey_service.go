@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *UserService) BeginPasskeyLogin(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "BeginPasskeyLogin")
+	defer span.Finish()
+
+	response, err := s.uc.BeginPasskeyLogin(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Begin Passkey Login",
+		Data:    response,
+	})
+}
+
+func (s *UserService) LoginPasskey(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "LoginPasskey")
+	defer span.Finish()
+
+	var request *model.RequestLoginPasskey
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.ChallengeToken == "" || len(request.Credential) == 0 {
+		utils.LogEventError(span, errors.New("challenge_token and credential shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("challenge_token and credential shouldn't be empty")), nil)
+	}
+
+	request.IPAddress = e.RealIP()
+	request.UserAgent = e.Request().UserAgent()
+
+	response, err := s.uc.LoginPasskey(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Login",
+		Data:    response,
+	})
+}
+
+func (s *UserService) GetPasskey(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetPasskey")
+	defer span.Finish()
+
+	response, err := s.uc.GetPasskey(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Passkey",
+		Data:    response,
+	})
+}
+
+func (s *UserService) BeginPasskeyRegistration(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "BeginPasskeyRegistration")
+	defer span.Finish()
+
+	response, err := s.uc.BeginPasskeyRegistration(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Begin Passkey Registration",
+		Data:    response,
+	})
+}
+
+func (s *UserService) FinishPasskeyRegistration(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "FinishPasskeyRegistration")
+	defer span.Finish()
+
+	var request *model.RequestPasskeyRegister
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if len(request.Credential) == 0 {
+		utils.LogEventError(span, errors.New("credential shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("credential shouldn't be empty")), nil)
+	}
+
+	response, err := s.uc.FinishPasskeyRegistration(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Register Passkey")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Register Passkey",
+		Data:    response,
+	})
+}
+
+func (s *UserService) DeletePasskey(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeletePasskey")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.DeletePasskey(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Passkey")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Passkey",
+		Data:    nil,
+	})
+}
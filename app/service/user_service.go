@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"face-recognition-svc/app/controller"
 	"face-recognition-svc/app/model"
 	"face-recognition-svc/app/utils"
@@ -13,6 +14,48 @@ type InterfaceUserService interface {
 	CreateNewUser(e echo.Context) error
 	GetUserDetail(e echo.Context) error
 	Login(e echo.Context) error
+	LoginMfa(e echo.Context) error
+	StartOIDCLogin(e echo.Context) error
+	OIDCCallback(e echo.Context) error
+	BeginPasskeyLogin(e echo.Context) error
+	LoginPasskey(e echo.Context) error
+	RefreshToken(e echo.Context) error
+	Logout(e echo.Context) error
+	LogoutAll(e echo.Context) error
+	ForceLogout(e echo.Context) error
+	UpdateUser(e echo.Context) error
+	UpdateUserStatus(e echo.Context) error
+	DeleteUser(e echo.Context) error
+	ResetPassword(e echo.Context) error
+	UnlockLogin(e echo.Context) error
+	ResetUserMfa(e echo.Context) error
+	GetUserSession(e echo.Context) error
+	GetLoginHistory(e echo.Context) error
+
+	GetProfile(e echo.Context) error
+	GetMyMenu(e echo.Context) error
+	UpdateProfile(e echo.Context) error
+	ChangePassword(e echo.Context) error
+	EnrollMfa(e echo.Context) error
+	ActivateMfa(e echo.Context) error
+	RegenerateRecoveryCode(e echo.Context) error
+	DisableMfa(e echo.Context) error
+	GetPasskey(e echo.Context) error
+	BeginPasskeyRegistration(e echo.Context) error
+	FinishPasskeyRegistration(e echo.Context) error
+	DeletePasskey(e echo.Context) error
+	GetMySession(e echo.Context) error
+	RevokeMySession(e echo.Context) error
+	GetMyLoginHistory(e echo.Context) error
+
+	Impersonate(e echo.Context) error
+	GetImpersonationAudit(e echo.Context) error
+
+	CreateInvitation(e echo.Context) error
+	GetAllInvitation(e echo.Context) error
+	DeleteInvitation(e echo.Context) error
+	AcceptInvitation(e echo.Context) error
+
 	GetAllUser(e echo.Context) error
 	GetInstitutionList(e echo.Context) error
 }
@@ -31,14 +74,14 @@ func (s *UserService) CreateNewUser(e echo.Context) error {
 	ctx, span := utils.StartSpan(e, "CreateNewUser")
 	defer span.Finish()
 
-	var request *model.User
+	var request *model.RequestRegister
 
 	if err := e.Bind(&request); err != nil {
 		utils.LogEventError(span, err)
 		return utils.LogError(e, err, nil)
 	}
 
-	utils.LogEvent(span, "Request", request)
+	utils.LogEvent(span, "Request", request.Username)
 
 	err := s.uc.CreateNewUser(ctx, request)
 	if err != nil {
@@ -87,7 +130,10 @@ func (s *UserService) Login(e echo.Context) error {
 		return utils.LogError(e, err, nil)
 	}
 
-	utils.LogEvent(span, "Request", request)
+	request.IPAddress = e.RealIP()
+	request.UserAgent = e.Request().UserAgent()
+
+	utils.LogEvent(span, "Request", request.Username)
 
 	response, err := s.uc.Login(ctx, request)
 	if err != nil {
@@ -102,6 +148,100 @@ func (s *UserService) Login(e echo.Context) error {
 	})
 }
 
+func (s *UserService) RefreshToken(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "RefreshToken")
+	defer span.Finish()
+
+	var request *model.RequestRefreshToken
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.RefreshToken == "" {
+		utils.LogEventError(span, errors.New("refresh_token shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("refresh_token shouldn't be empty")), nil)
+	}
+
+	response, err := s.uc.RefreshToken(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Refresh Token",
+		Data:    response,
+	})
+}
+
+func (s *UserService) Logout(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "Logout")
+	defer span.Finish()
+
+	err := s.uc.Logout(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Logout")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Logout",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) LogoutAll(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "LogoutAll")
+	defer span.Finish()
+
+	err := s.uc.LogoutAll(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Logout All Sessions")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Logout All Sessions",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) ForceLogout(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "ForceLogout")
+	defer span.Finish()
+
+	username := e.Param("id")
+	if username == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", username)
+
+	err := s.uc.ForceLogout(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Force Logout")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Force Logout",
+		Data:    nil,
+	})
+}
+
 func (s *UserService) GetAllUser(e echo.Context) error {
 	ctx, span := utils.StartSpan(e, "GetAlluser")
 	defer span.Finish()
@@ -139,3 +279,366 @@ func (s *UserService) GetInstitutionList(e echo.Context) error {
 		Data:    institutionList,
 	})
 }
+
+func (s *UserService) UpdateUser(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateUser")
+	defer span.Finish()
+
+	var request *model.RequestUpdateUser
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Username == "" || request.RoleID == "" {
+		utils.LogEventError(span, errors.New("username and role_id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("username and role_id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.UpdateUser(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update User")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update User",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) UpdateUserStatus(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateUserStatus")
+	defer span.Finish()
+
+	var request *model.RequestUserStatus
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Username == "" {
+		utils.LogEventError(span, errors.New("username shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("username shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.UpdateUserStatus(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update User Status")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update User Status",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) DeleteUser(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteUser")
+	defer span.Finish()
+
+	username := e.Param("id")
+	if username == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", username)
+
+	err := s.uc.DeleteUser(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete User")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete User",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) ResetPassword(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "ResetPassword")
+	defer span.Finish()
+
+	username := e.Param("id")
+	if username == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", username)
+
+	response, err := s.uc.ResetPassword(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Reset Password")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Reset Password",
+		Data:    response,
+	})
+}
+
+func (s *UserService) UnlockLogin(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UnlockLogin")
+	defer span.Finish()
+
+	username := e.Param("id")
+	if username == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	ip := e.QueryParam("ip")
+
+	utils.LogEvent(span, "Request", username)
+
+	err := s.uc.UnlockLogin(ctx, username, ip)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Unlock Login")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Unlock Login",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) GetProfile(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetProfile")
+	defer span.Finish()
+
+	user, err := s.uc.GetProfile(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", user)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Profile",
+		Data:    user,
+	})
+}
+
+func (s *UserService) GetMyMenu(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetMyMenu")
+	defer span.Finish()
+
+	menu, err := s.uc.GetMyMenu(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", menu)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Menu",
+		Data:    menu,
+	})
+}
+
+func (s *UserService) UpdateProfile(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateProfile")
+	defer span.Finish()
+
+	var request *model.RequestUpdateProfile
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Email == "" || request.Fullname == "" {
+		utils.LogEventError(span, errors.New("email and fullname shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("email and fullname shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	err := s.uc.UpdateProfile(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Update Profile")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update Profile",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) ChangePassword(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "ChangePassword")
+	defer span.Finish()
+
+	var request *model.RequestChangePassword
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.CurrentPassword == "" || request.NewPassword == "" {
+		utils.LogEventError(span, errors.New("current_password and new_password shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("current_password and new_password shouldn't be empty")), nil)
+	}
+
+	err := s.uc.ChangePassword(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Change Password")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Change Password",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) CreateInvitation(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CreateInvitation")
+	defer span.Finish()
+
+	var request *model.RequestCreateInvitation
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Email == "" || request.RoleID == "" || request.InstitutionID == "" {
+		utils.LogEventError(span, errors.New("email, role_id and institution_id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("email, role_id and institution_id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	response, err := s.uc.CreateInvitation(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Create Invitation")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Create Invitation",
+		Data:    response,
+	})
+}
+
+func (s *UserService) GetAllInvitation(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetAllInvitation")
+	defer span.Finish()
+
+	invitations, err := s.uc.GetAllInvitation(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", invitations)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get All Invitation",
+		Data:    invitations,
+	})
+}
+
+func (s *UserService) DeleteInvitation(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteInvitation")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.DeleteInvitation(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Delete Invitation")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Invitation",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) AcceptInvitation(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "AcceptInvitation")
+	defer span.Finish()
+
+	var request *model.RequestAcceptInvitation
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	if request.Token == "" || request.Username == "" || request.Password == "" {
+		utils.LogEventError(span, errors.New("token, username and password shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("token, username and password shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", request.Username)
+
+	err := s.uc.AcceptInvitation(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Accept Invitation")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Accept Invitation",
+		Data:    nil,
+	})
+}
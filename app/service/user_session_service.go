@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/app/model"
+	"face-recognition-svc/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *UserService) GetMySession(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetMySession")
+	defer span.Finish()
+
+	response, err := s.uc.GetMySession(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Session",
+		Data:    response,
+	})
+}
+
+func (s *UserService) RevokeMySession(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "RevokeMySession")
+	defer span.Finish()
+
+	id := e.Param("id")
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.RevokeMySession(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", "Success Revoke Session")
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Revoke Session",
+		Data:    nil,
+	})
+}
+
+func (s *UserService) GetMyLoginHistory(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetMyLoginHistory")
+	defer span.Finish()
+
+	var request *model.RequestLoginHistory
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	response, err := s.uc.GetMyLoginHistory(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Login History",
+		Data:    response,
+	})
+}
+
+func (s *UserService) GetUserSession(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetUserSession")
+	defer span.Finish()
+
+	username := e.Param("id")
+	if username == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("id shouldn't be empty")), nil)
+	}
+
+	utils.LogEvent(span, "Request", username)
+
+	response, err := s.uc.GetUserSession(ctx, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Session",
+		Data:    response,
+	})
+}
+
+func (s *UserService) GetLoginHistory(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetLoginHistory")
+	defer span.Finish()
+
+	var request *model.RequestLoginHistory
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	response, err := s.uc.GetLoginHistory(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Login History",
+		Data:    response,
+	})
+}
@@ -1,38 +1,240 @@
 package utils
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"face-recognition-svc/app/model"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc/metadata"
 )
 
-func IsAuthorized() echo.MiddlewareFunc {
+const (
+	revokedTokenKey   = "revoked_token:%s"
+	revokedSessionKey = "revoked_session:%s"
+
+	ApiKeyScheme = "ApiKey"
+)
+
+// RevokeToken puts a token id on the denylist until the token would have expired anyway.
+func RevokeToken(ctx context.Context, rdb *redis.Client, tokenID string, ttl time.Duration) error {
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+	return rdb.Set(ctx, fmt.Sprintf(revokedTokenKey, tokenID), 1, ttl).Err()
+}
+
+// RevokeSession puts a login session on the denylist, rejecting every access token issued for it.
+func RevokeSession(ctx context.Context, rdb *redis.Client, sessionID string, ttl time.Duration) error {
+	if sessionID == "" || ttl <= 0 {
+		return nil
+	}
+	return rdb.Set(ctx, fmt.Sprintf(revokedSessionKey, sessionID), 1, ttl).Err()
+}
+
+// IsTokenActive rejects tokens whose jti or session has been revoked and exposes the
+// token identity to the handlers through the request metadata.
+func IsTokenActive(rdb *redis.Client) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			token := c.Get("user").(*jwt.Token)
 			claims := token.Claims.(*model.JwtCustomClaims)
-			access := strings.Split(claims.MenuMapping[c.Request().Header.Get("app-menu-id")], ",")
-			if c.Request().Header.Get("app-menu-id") != claims.Role {
-				return LogError(c, model.ThrowError(http.StatusForbidden, errors.New("Anda Tidak Memiliki Akses")), nil)
+
+			keys := []string{fmt.Sprintf(revokedTokenKey, claims.ID)}
+			if claims.SessionID != "" {
+				keys = append(keys, fmt.Sprintf(revokedSessionKey, claims.SessionID))
+			}
+			// Impersonation ends with the session of the administrator who started it.
+			if claims.Act != nil && claims.Act.SessionID != "" {
+				keys = append(keys, fmt.Sprintf(revokedSessionKey, claims.Act.SessionID))
 			}
-			if !Contains(access, c.Request().Method) {
-				return LogError(c, model.ThrowError(http.StatusForbidden, errors.New("Method Not Allowed")), nil)
+
+			revoked, err := rdb.Exists(c.Request().Context(), keys...).Result()
+			if err != nil {
+				return LogError(c, model.ThrowError(http.StatusServiceUnavailable, err), nil)
+			}
+			if revoked > 0 {
+				return LogError(c, model.ThrowError(http.StatusUnauthorized, errors.New("token has been revoked")), nil)
+			}
+
+			var expiredAt int64
+			if claims.ExpiresAt != nil {
+				expiredAt = claims.ExpiresAt.Unix()
 			}
 
 			md := metadata.New(map[string]string{
-				"username": claims.Name,
-				"role_id":  claims.Role,
+				"username":       claims.Name,
+				"role_id":        claims.Role,
+				"institution_id": claims.Institution,
+				"token_id":       claims.ID,
+				"session_id":     claims.SessionID,
+				"expired_at":     strconv.FormatInt(expiredAt, 10),
+				"amr":            strings.Join(claims.Amr, ","),
 			})
 
+			if claims.Act != nil {
+				md.Set("actor", claims.Act.Subject)
+				md.Set("actor_session_id", claims.Act.SessionID)
+				md.Set("read_only", strconv.FormatBool(claims.Act.ReadOnly))
+			}
+
 			c.SetRequest(c.Request().WithContext(metadata.NewIncomingContext(c.Request().Context(), md)))
 
 			return next(c)
 		}
 	}
 }
+
+type TenantResolver interface {
+	IsCrossTenant(ctx context.Context, claims *model.JwtCustomClaims) (bool, error)
+}
+
+// IsTenantScoped limits every query made while handling the request to the caller's
+// institution, unless the caller's role may act across institutions.
+func IsTenantScoped(resolver TenantResolver) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := c.Get("user").(*jwt.Token)
+			claims := token.Claims.(*model.JwtCustomClaims)
+
+			crossTenant, err := resolver.IsCrossTenant(c.Request().Context(), claims)
+			if err != nil {
+				return LogError(c, err, nil)
+			}
+
+			if !crossTenant {
+				c.SetRequest(c.Request().WithContext(WithTenant(c.Request().Context(), claims.Institution)))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+type ImpersonationAuditor interface {
+	AuditImpersonation(ctx context.Context, audit *model.ImpersonationAudit)
+}
+
+// IsImpersonationAllowed records every request made with an impersonation token and
+// refuses mutations under the protected route prefixes, or anywhere when the token is
+// read-only. Logging out at logoutRoute is always allowed so the impersonation can end.
+// Requests with regular tokens are passed on untouched.
+func IsImpersonationAllowed(auditor ImpersonationAuditor, logoutRoute string, protected ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := c.Get("user").(*jwt.Token)
+			claims := token.Claims.(*model.JwtCustomClaims)
+
+			if claims.Act == nil {
+				return next(c)
+			}
+
+			audit := &model.ImpersonationAudit{
+				Actor:     claims.Act.Subject,
+				Username:  claims.Name,
+				SessionID: claims.SessionID,
+				Method:    c.Request().Method,
+				Path:      c.Request().URL.Path,
+				IPAddress: c.RealIP(),
+				CreatedAt: time.Now(),
+			}
+
+			if isMutation(audit.Method) && c.Path() != logoutRoute {
+				blocked := claims.Act.ReadOnly
+				for _, prefix := range protected {
+					if c.Path() == prefix || strings.HasPrefix(c.Path(), prefix+"/") {
+						blocked = true
+					}
+				}
+
+				if blocked {
+					audit.Status = http.StatusForbidden
+					audit.Blocked = true
+					auditor.AuditImpersonation(c.Request().Context(), audit)
+
+					return LogError(c, model.ThrowError(http.StatusForbidden, errors.New("not allowed while impersonating")), nil)
+				}
+			}
+
+			err := next(c)
+
+			audit.Status = c.Response().Status
+			if err != nil {
+				audit.Status = http.StatusInternalServerError
+				if data, ok := err.(*echo.HTTPError); ok {
+					audit.Status = data.Code
+				}
+			}
+			auditor.AuditImpersonation(c.Request().Context(), audit)
+
+			return err
+		}
+	}
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// ApiKeyAuthenticator resolves an API key to the claims of its service account.
+type ApiKeyAuthenticator interface {
+	AuthenticateApiKey(ctx context.Context, key string) (*model.JwtCustomClaims, error)
+}
+
+// IsApiKeyValid authenticates requests carrying an "Authorization: ApiKey <key>" header and
+// stores the resulting claims where the JWT middleware would, so the rest of the chain
+// treats API keys and access tokens alike. Other requests are passed on untouched.
+func IsApiKeyValid(authenticator ApiKeyAuthenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scheme, key, found := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if !found || !strings.EqualFold(scheme, ApiKeyScheme) {
+				return next(c)
+			}
+
+			claims, err := authenticator.AuthenticateApiKey(c.Request().Context(), strings.TrimSpace(key))
+			if err != nil {
+				return LogError(c, err, nil)
+			}
+
+			c.Set("user", &jwt.Token{Claims: claims, Valid: true})
+
+			return next(c)
+		}
+	}
+}
+
+// Authorizer decides whether a role may call a route with the given method.
+type Authorizer interface {
+	Authorize(ctx context.Context, claims *model.JwtCustomClaims, route string, method string) error
+}
+
+// IsAuthorized checks the matched Echo route, stripped of prefix, and the request method
+// against the menus mapped to the caller's role.
+func IsAuthorized(prefix string, authorizer Authorizer) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := c.Get("user").(*jwt.Token)
+			claims := token.Claims.(*model.JwtCustomClaims)
+
+			route := strings.TrimPrefix(c.Path(), prefix)
+			if err := authorizer.Authorize(c.Request().Context(), claims, route, c.Request().Method); err != nil {
+				return LogError(c, err, nil)
+			}
+
+			return next(c)
+		}
+	}
+}
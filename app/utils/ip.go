@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	conf "face-recognition-svc/app/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewIPExtractor returns how e.RealIP() finds the client address. Forwarding headers are
+// only believed when they were set by one of the configured trusted proxies, anyone else
+// could put any address in them to dodge the login lockout or the audit trail.
+func NewIPExtractor(c *conf.Config) (echo.IPExtractor, error) {
+	if len(c.Listener.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+
+	for _, proxy := range c.Listener.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			if ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	conf "face-recognition-svc/app/config"
+	"face-recognition-svc/app/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet holds the keys access tokens are signed and verified with.
+type KeySet struct {
+	activeKeyID string
+	secret      []byte
+	legacyUntil time.Time
+	keys        map[string]*signingKey
+}
+
+type signingKey struct {
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+var Keys = &KeySet{keys: map[string]*signingKey{}}
+
+// InitKeySet loads the signing keys from the auth config.
+func InitKeySet(c *conf.Config) error {
+	keySet := &KeySet{
+		activeKeyID: c.Auth.ActiveKeyID,
+		secret:      []byte(c.Auth.AccessSecret),
+		keys:        map[string]*signingKey{},
+	}
+
+	if c.Auth.LegacyTokensUntil != "" {
+		until, err := time.Parse(time.RFC3339, c.Auth.LegacyTokensUntil)
+		if err != nil {
+			return fmt.Errorf("invalid legacyTokensUntil: %w", err)
+		}
+		keySet.legacyUntil = until
+	}
+
+	for _, k := range c.Auth.SigningKeys {
+		if k.ID == "" {
+			return errors.New("signing key is missing id")
+		}
+		if _, ok := keySet.keys[k.ID]; ok {
+			return fmt.Errorf("duplicate signing key %s", k.ID)
+		}
+
+		key, err := loadSigningKey(k)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", k.ID, err)
+		}
+		keySet.keys[k.ID] = key
+	}
+
+	if keySet.activeKeyID != "" {
+		key, ok := keySet.keys[keySet.activeKeyID]
+		if !ok {
+			return fmt.Errorf("active signing key %s is not configured", keySet.activeKeyID)
+		}
+		if key.private == nil {
+			return fmt.Errorf("active signing key %s has no private key", keySet.activeKeyID)
+		}
+	}
+
+	Keys = keySet
+	return nil
+}
+
+func loadSigningKey(k conf.SigningKey) (*signingKey, error) {
+	privatePEM, err := readKey(k.PrivateKey, k.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	publicPEM, err := readKey(k.PublicKey, k.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if privatePEM == nil && publicPEM == nil {
+		return nil, errors.New("no key material")
+	}
+
+	key := &signingKey{}
+
+	switch k.Algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		key.method = jwt.SigningMethodRS256
+		if privatePEM != nil {
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.private, key.public = private, &private.PublicKey
+		} else {
+			key.public, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		key.method = jwt.SigningMethodEdDSA
+		if privatePEM != nil {
+			private, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.private, key.public = private, private.(ed25519.PrivateKey).Public()
+		} else {
+			key.public, err = jwt.ParseEdPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", k.Algorithm)
+	}
+
+	return key, nil
+}
+
+func readKey(value string, file string) ([]byte, error) {
+	if value != "" {
+		return []byte(value), nil
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// Sign signs claims with the active key, or with the shared secret when no key is active.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if k.activeKeyID == "" {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	key := k.keys[k.activeKeyID]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = k.activeKeyID
+
+	return token.SignedString(key.private)
+}
+
+// Keyfunc returns the verification key for the kid in the token header. Tokens without a
+// kid are verified with the shared secret while no key is active, and after the switch to
+// asymmetric keys only until the configured cut-off, so that the secret can't be used to
+// mint tokens forever.
+func (k *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		if t.Method.Alg() != jwt.SigningMethodHS256.Alg() || len(k.secret) == 0 {
+			return nil, errors.New("token is missing kid")
+		}
+		if k.activeKeyID != "" && !time.Now().Before(k.legacyUntil) {
+			return nil, errors.New("token is missing kid")
+		}
+		return k.secret, nil
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+
+	return key.public, nil
+}
+
+// JWKS returns the public keys so that other services can verify access tokens without
+// sharing a secret.
+func (k *KeySet) JWKS() *model.JSONWebKeySet {
+	res := &model.JSONWebKeySet{Keys: []model.JSONWebKey{}}
+
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	for _, kid := range kids {
+		key := k.keys[kid]
+		jwk := model.JSONWebKey{
+			Kid: kid,
+			Use: "sig",
+			Alg: key.method.Alg(),
+		}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		res.Keys = append(res.Keys, jwk)
+	}
+
+	return res
+}
@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	conf "face-recognition-svc/app/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// PasswordHasher hashes new passwords with the configured algorithm and verifies hashes
+// made with any supported one. Hashes are self-describing: bcrypt hashes carry their cost
+// and argon2id hashes use the PHC string format with their parameters.
+type PasswordHasher struct {
+	algorithm     string
+	bcryptCost    int
+	argon2Memory  uint32
+	argon2Time    uint32
+	argon2Threads uint8
+	blocklist     map[string]struct{}
+}
+
+var Passwords = &PasswordHasher{
+	algorithm:  PasswordAlgorithmBcrypt,
+	bcryptCost: bcrypt.DefaultCost,
+	blocklist:  map[string]struct{}{},
+}
+
+// InitPasswordHasher loads the hashing settings and the blocklist from the auth config.
+func InitPasswordHasher(c *conf.Config) error {
+	cfg := c.Auth.Password
+
+	hasher := &PasswordHasher{
+		algorithm:     strings.ToLower(cfg.Algorithm),
+		bcryptCost:    cfg.BcryptCost,
+		argon2Memory:  cfg.Argon2Memory,
+		argon2Time:    cfg.Argon2Time,
+		argon2Threads: cfg.Argon2Threads,
+		blocklist:     map[string]struct{}{},
+	}
+
+	if hasher.algorithm == "" {
+		hasher.algorithm = PasswordAlgorithmBcrypt
+	}
+	if hasher.bcryptCost == 0 {
+		hasher.bcryptCost = bcrypt.DefaultCost
+	}
+	if hasher.argon2Memory == 0 {
+		hasher.argon2Memory = 64 * 1024
+	}
+	if hasher.argon2Time == 0 {
+		hasher.argon2Time = 3
+	}
+	if hasher.argon2Threads == 0 {
+		hasher.argon2Threads = 2
+	}
+
+	switch hasher.algorithm {
+	case PasswordAlgorithmBcrypt:
+		if hasher.bcryptCost < bcrypt.MinCost || hasher.bcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case PasswordAlgorithmArgon2id:
+	default:
+		return fmt.Errorf("unsupported password algorithm %s", cfg.Algorithm)
+	}
+
+	if cfg.BlocklistFile != "" {
+		file, err := os.Open(cfg.BlocklistFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				hasher.blocklist[strings.ToLower(line)] = struct{}{}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	Passwords = hasher
+	return nil
+}
+
+// MaxLength returns the longest password in bytes the current algorithm can hash.
+func (h *PasswordHasher) MaxLength() int {
+	if h.algorithm == PasswordAlgorithmBcrypt {
+		return 72
+	}
+	return 1024
+}
+
+// IsBlocked reports whether a password is on the blocklist of common passwords.
+func (h *PasswordHasher) IsBlocked(password string) bool {
+	_, ok := h.blocklist[strings.ToLower(password)]
+	return ok
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.algorithm == PasswordAlgorithmArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+
+		key := argon2.IDKey([]byte(password), salt, h.argon2Time, h.argon2Memory, h.argon2Threads, argon2KeyLength)
+
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.argon2Memory, h.argon2Time, h.argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Verify checks a password against a hash made with any supported algorithm. When the
+// password matches but the hash was made with another algorithm or weaker parameters
+// than configured, rehash is true and the password should be hashed again.
+func (h *PasswordHasher) Verify(hash string, password string) (ok bool, rehash bool) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := parseArgon2Hash(hash)
+		if err != nil {
+			return false, false
+		}
+
+		candidate := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return false, false
+		}
+
+		return true, h.algorithm != PasswordAlgorithmArgon2id || params.memory != h.argon2Memory || params.time != h.argon2Time || params.threads != h.argon2Threads
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true, true
+	}
+
+	return true, h.algorithm != PasswordAlgorithmBcrypt || cost < h.bcryptCost
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func parseArgon2Hash(hash string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errors.New("unsupported argon2id version")
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return params, salt, key, nil
+}
+
+// GeneratePassword returns a random password of the given length with at least one
+// lowercase letter, uppercase letter, digit and symbol.
+func GeneratePassword(length int) (string, error) {
+	classes := []string{
+		"abcdefghijkmnopqrstuvwxyz",
+		"ABCDEFGHJKLMNPQRSTUVWXYZ",
+		"23456789",
+		"!@#$%^&*-_=+?",
+	}
+	all := strings.Join(classes, "")
+
+	if length < len(classes) {
+		length = len(classes)
+	}
+
+	password := make([]byte, length)
+	for i := range password {
+		set := all
+		if i < len(classes) {
+			set = classes[i]
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = set[n.Int64()]
+	}
+
+	// Move the guaranteed characters away from the front.
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "context"
+
+type tenantKey struct{}
+
+// WithTenant limits the queries made with ctx to the rows of one institution. An empty
+// institution id limits them to rows without an institution.
+func WithTenant(ctx context.Context, institutionID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, institutionID)
+}
+
+// GetTenant returns the institution the queries made with ctx are limited to. ok is false
+// for calls that may act across institutions, such as those of a super admin or those
+// made before the caller is known.
+func GetTenant(ctx context.Context) (institutionID string, ok bool) {
+	institutionID, ok = ctx.Value(tenantKey{}).(string)
+	return institutionID, ok
+}
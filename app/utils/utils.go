@@ -2,9 +2,14 @@ package utils
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"face-recognition-svc/app/model"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/labstack/echo/v4"
@@ -45,6 +50,38 @@ func GetMetadata(c context.Context) (*model.MetadataUser, error) {
 		metaData.RoleID = sanitizer(t[0])
 	}
 
+	if t, ok := md["institution_id"]; ok {
+		metaData.InstitutionID = sanitizer(t[0])
+	}
+
+	if t, ok := md["token_id"]; ok {
+		metaData.TokenID = sanitizer(t[0])
+	}
+
+	if t, ok := md["session_id"]; ok {
+		metaData.SessionID = sanitizer(t[0])
+	}
+
+	if t, ok := md["expired_at"]; ok {
+		metaData.ExpiredAt, _ = strconv.ParseInt(t[0], 10, 64)
+	}
+
+	if t, ok := md["amr"]; ok && t[0] != "" {
+		metaData.Amr = strings.Split(sanitizer(t[0]), ",")
+	}
+
+	if t, ok := md["actor"]; ok {
+		metaData.Actor = sanitizer(t[0])
+	}
+
+	if t, ok := md["actor_session_id"]; ok {
+		metaData.ActorSessionID = sanitizer(t[0])
+	}
+
+	if t, ok := md["read_only"]; ok {
+		metaData.ReadOnly, _ = strconv.ParseBool(t[0])
+	}
+
 	return metaData, nil
 }
 
@@ -75,3 +112,28 @@ func Contains(arr []string, str string) bool {
 	}
 	return false
 }
+
+// MatchRoute reports whether route is menuRoute itself or one of the routes below it.
+func MatchRoute(route string, menuRoute string) bool {
+	menuRoute = strings.TrimSuffix(menuRoute, "/")
+	if menuRoute == "" {
+		return false
+	}
+	return route == menuRoute || strings.HasPrefix(route, menuRoute+"/")
+}
+
+// GenerateRandomToken returns a URL-safe random string built from size random bytes.
+func GenerateRandomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a random token, suitable for storing
+// and looking up high-entropy secrets without keeping them in plain text.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN is_active TINYINT(1) NOT NULL DEFAULT 1 AFTER institution_id,
+    ADD COLUMN updated_at DATETIME NULL AFTER created_at,
+    ADD COLUMN updated_by VARCHAR(200) NULL AFTER updated_at;
@@ -0,0 +1,17 @@
+CREATE TABLE user_invitation (
+    id             VARCHAR(36)  NOT NULL PRIMARY KEY,
+    token_hash     CHAR(64)     NOT NULL UNIQUE,
+    email          VARCHAR(200) NOT NULL,
+    role_id        VARCHAR(36)  NOT NULL,
+    institution_id VARCHAR(200) NOT NULL,
+    expired_at     DATETIME     NOT NULL,
+    used_at        DATETIME     NULL,
+    used_by        VARCHAR(200) NULL,
+    created_at     DATETIME     NOT NULL,
+    created_by     VARCHAR(200) NOT NULL
+);
+
+INSERT INTO parameter (id, value, description, updated_at, updated_by) VALUES
+    ('REGISTRATION_MODE', 'invitation', 'invitation: accounts are created through admin invitations only, open: anyone may register with the default role', NOW(), 'system'),
+    ('REGISTRATION_DEFAULT_ROLE', '', 'Role id assigned to self-registered users when REGISTRATION_MODE is open', NOW(), 'system'),
+    ('REGISTRATION_DEFAULT_INSTITUTION', '', 'Institution id assigned to self-registered users when REGISTRATION_MODE is open', NOW(), 'system');
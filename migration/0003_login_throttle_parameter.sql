@@ -0,0 +1,7 @@
+INSERT INTO parameter (id, value, description, updated_at, updated_by) VALUES
+    ('LOGIN_ATTEMPT_WINDOW_MINUTES', '15', 'How long failed login attempts are remembered', NOW(), 'system'),
+    ('LOGIN_DELAY_THRESHOLD', '3', 'Failed attempts per username before each further attempt is delayed', NOW(), 'system'),
+    ('LOGIN_DELAY_SECONDS', '1', 'Base delay after the threshold is reached, doubled on every further failure', NOW(), 'system'),
+    ('LOGIN_MAX_ATTEMPT', '5', 'Failed attempts per username before the account is temporarily locked', NOW(), 'system'),
+    ('LOGIN_MAX_ATTEMPT_IP', '20', 'Failed attempts per IP address before the address is temporarily locked', NOW(), 'system'),
+    ('LOGIN_LOCKOUT_MINUTES', '15', 'How long a username or IP address stays locked', NOW(), 'system');
@@ -0,0 +1,20 @@
+ALTER TABLE role
+    ADD COLUMN mfa_required TINYINT(1) NOT NULL DEFAULT 0 AFTER is_active;
+
+CREATE TABLE user_mfa (
+    username   VARCHAR(200) NOT NULL PRIMARY KEY,
+    secret     VARCHAR(64)  NOT NULL,
+    is_enabled TINYINT(1)   NOT NULL DEFAULT 0,
+    created_at DATETIME     NOT NULL,
+    enabled_at DATETIME     NULL
+);
+
+CREATE TABLE user_mfa_recovery_code (
+    username   VARCHAR(200) NOT NULL,
+    code_hash  CHAR(64)     NOT NULL,
+    created_at DATETIME     NOT NULL,
+    PRIMARY KEY (username, code_hash)
+);
+
+INSERT INTO parameter (id, value, description, updated_at, updated_by) VALUES
+    ('MFA_ISSUER', 'Face Recognition', 'Issuer name shown by authenticator apps for TOTP enrollments', NOW(), 'system');
@@ -0,0 +1,26 @@
+CREATE TABLE service_account (
+    id             VARCHAR(36)  NOT NULL PRIMARY KEY,
+    name           VARCHAR(200) NOT NULL UNIQUE,
+    description    VARCHAR(500) NULL,
+    role_id        VARCHAR(36)  NOT NULL,
+    institution_id VARCHAR(200) NOT NULL,
+    is_active      TINYINT(1)   NOT NULL DEFAULT 1,
+    created_at     DATETIME     NOT NULL,
+    created_by     VARCHAR(200) NOT NULL,
+    updated_at     DATETIME     NOT NULL,
+    updated_by     VARCHAR(200) NOT NULL
+);
+
+CREATE TABLE api_key (
+    id                 VARCHAR(36)  NOT NULL PRIMARY KEY,
+    service_account_id VARCHAR(36)  NOT NULL,
+    key_prefix         VARCHAR(16)  NOT NULL,
+    key_hash           CHAR(64)     NOT NULL UNIQUE,
+    expired_at         DATETIME     NULL,
+    last_used_at       DATETIME     NULL,
+    revoked_at         DATETIME     NULL,
+    revoked_by         VARCHAR(200) NULL,
+    created_at         DATETIME     NOT NULL,
+    created_by         VARCHAR(200) NOT NULL,
+    INDEX idx_api_key_service_account (service_account_id)
+);
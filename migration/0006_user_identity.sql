@@ -0,0 +1,8 @@
+CREATE TABLE user_identity (
+    provider   VARCHAR(200) NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    username   VARCHAR(200) NOT NULL,
+    created_at DATETIME     NOT NULL,
+    PRIMARY KEY (provider, subject),
+    INDEX idx_user_identity_username (username)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN auth_source VARCHAR(20) NOT NULL DEFAULT 'local' AFTER password;
+
+UPDATE users u
+    JOIN user_identity i ON i.username = u.username
+SET u.auth_source = 'oidc'
+WHERE u.password = '!';
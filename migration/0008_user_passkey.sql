@@ -0,0 +1,17 @@
+CREATE TABLE user_passkey (
+    id               VARCHAR(36)   NOT NULL PRIMARY KEY,
+    credential_id    VARCHAR(1400) CHARACTER SET ascii NOT NULL,
+    username         VARCHAR(200)  NOT NULL,
+    name             VARCHAR(200)  NOT NULL,
+    public_key       VARBINARY(1024) NOT NULL,
+    attestation_type VARCHAR(50)   NOT NULL,
+    transports       VARCHAR(200)  NOT NULL DEFAULT '',
+    aaguid           VARCHAR(36)   NOT NULL DEFAULT '',
+    sign_count       INT UNSIGNED  NOT NULL DEFAULT 0,
+    backup_eligible  TINYINT(1)    NOT NULL DEFAULT 0,
+    backup_state     TINYINT(1)    NOT NULL DEFAULT 0,
+    created_at       DATETIME      NOT NULL,
+    last_used_at     DATETIME      NULL,
+    UNIQUE INDEX idx_user_passkey_credential (credential_id),
+    INDEX idx_user_passkey_username (username)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE login_history (
+    id             BIGINT        NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    username       VARCHAR(200)  NOT NULL,
+    institution_id VARCHAR(200)  NULL,
+    session_id     VARCHAR(36)   NULL,
+    method         VARCHAR(20)   NOT NULL,
+    outcome        VARCHAR(20)   NOT NULL,
+    reason         VARCHAR(255)  NOT NULL DEFAULT '',
+    ip_address     VARCHAR(45)   NOT NULL DEFAULT '',
+    user_agent     VARCHAR(500)  NOT NULL DEFAULT '',
+    created_at     DATETIME      NOT NULL,
+    INDEX idx_login_history_username (username, created_at),
+    INDEX idx_login_history_institution (institution_id, created_at),
+    INDEX idx_login_history_created (created_at)
+);
@@ -0,0 +1,16 @@
+-- argon2id hashes are longer than the 60 characters of a bcrypt hash.
+ALTER TABLE users
+    MODIFY COLUMN password VARCHAR(255) NOT NULL;
+
+CREATE TABLE password_history (
+    id         BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    username   VARCHAR(200) NOT NULL,
+    password   VARCHAR(255) NOT NULL,
+    created_at DATETIME     NOT NULL,
+    INDEX idx_password_history_username (username, id)
+);
+
+INSERT INTO parameter (id, value, description, updated_at, updated_by) VALUES
+    ('PASSWORD_MIN_LENGTH', '10', 'Minimum number of characters of a new password', NOW(), 'system'),
+    ('PASSWORD_MIN_CLASSES', '3', 'How many of lowercase letters, uppercase letters, digits and symbols a new password must contain', NOW(), 'system'),
+    ('PASSWORD_HISTORY', '5', 'A new password may not match the current or this many minus one previous passwords, 0 disables the check', NOW(), 'system');
@@ -0,0 +1,24 @@
+CREATE TABLE impersonation_audit (
+    id         BIGINT        NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    actor      VARCHAR(200)  NOT NULL,
+    username   VARCHAR(200)  NOT NULL,
+    session_id VARCHAR(36)   NOT NULL,
+    method     VARCHAR(10)   NOT NULL DEFAULT '',
+    path       VARCHAR(500)  NOT NULL DEFAULT '',
+    status     INT           NOT NULL,
+    blocked    BOOLEAN       NOT NULL DEFAULT FALSE,
+    reason     VARCHAR(500)  NOT NULL DEFAULT '',
+    ip_address VARCHAR(45)   NOT NULL DEFAULT '',
+    created_at DATETIME      NOT NULL,
+    INDEX idx_impersonation_audit_actor (actor, created_at),
+    INDEX idx_impersonation_audit_username (username, created_at),
+    INDEX idx_impersonation_audit_session (session_id)
+);
+
+-- POST starts impersonating, GET reads the audit trail. Map it to support roles only.
+INSERT INTO menu (id, menu_name, menu_route, created_at, updated_at, created_by, updated_by) VALUES
+    (UUID(), 'Impersonation', '/impersonation', NOW(), NOW(), 'system', 'system');
+
+INSERT INTO parameter (id, value, description, updated_at, updated_by) VALUES
+    ('IMPERSONATION_MINUTES', '15', 'Lifetime in minutes of an impersonation token', NOW(), 'system'),
+    ('IMPERSONATION_READ_ONLY', 'true', 'When true every impersonation is read-only, when false the read_only flag of the request decides', NOW(), 'system');
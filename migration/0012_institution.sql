@@ -0,0 +1,47 @@
+CREATE TABLE institution (
+    id             VARCHAR(200) NOT NULL PRIMARY KEY,
+    name           VARCHAR(200) NOT NULL,
+    code           VARCHAR(200) NOT NULL UNIQUE,
+    timezone       VARCHAR(64)  NOT NULL DEFAULT 'UTC',
+    status         VARCHAR(20)  NOT NULL DEFAULT 'active',
+    contact_name   VARCHAR(200) NOT NULL DEFAULT '',
+    contact_email  VARCHAR(200) NOT NULL DEFAULT '',
+    contact_phone  VARCHAR(50)  NOT NULL DEFAULT '',
+    storage_bucket VARCHAR(63)  NOT NULL DEFAULT '',
+    created_at     DATETIME     NOT NULL,
+    created_by     VARCHAR(200) NOT NULL,
+    updated_at     DATETIME     NOT NULL,
+    updated_by     VARCHAR(200) NOT NULL
+);
+
+-- Empty institution ids mean "no institution" from now on.
+ALTER TABLE users
+    MODIFY COLUMN institution_id VARCHAR(200) NULL;
+UPDATE users SET institution_id = NULL WHERE institution_id = '';
+
+ALTER TABLE model_trainings
+    MODIFY COLUMN institution_id VARCHAR(200) NULL;
+UPDATE model_trainings SET institution_id = NULL WHERE institution_id = '';
+
+ALTER TABLE face_datasets
+    ADD COLUMN institution_id VARCHAR(200) NULL AFTER username;
+UPDATE face_datasets AS d JOIN users AS u ON d.username = u.username SET d.institution_id = u.institution_id;
+
+-- Register every institution id already in use, named after itself until renamed.
+INSERT INTO institution (id, name, code, created_at, created_by, updated_at, updated_by)
+SELECT institution_id, institution_id, institution_id, NOW(), 'system', NOW(), 'system'
+FROM (
+    SELECT institution_id FROM users WHERE institution_id IS NOT NULL
+    UNION
+    SELECT institution_id FROM model_trainings WHERE institution_id IS NOT NULL
+) AS used;
+
+ALTER TABLE users
+    ADD CONSTRAINT fk_users_institution FOREIGN KEY (institution_id) REFERENCES institution (id);
+ALTER TABLE face_datasets
+    ADD CONSTRAINT fk_face_datasets_institution FOREIGN KEY (institution_id) REFERENCES institution (id);
+ALTER TABLE model_trainings
+    ADD CONSTRAINT fk_model_trainings_institution FOREIGN KEY (institution_id) REFERENCES institution (id);
+
+INSERT INTO menu (id, menu_name, menu_route, created_at, updated_at, created_by, updated_by) VALUES
+    (UUID(), 'Institution', '/institution', NOW(), NOW(), 'system', 'system');
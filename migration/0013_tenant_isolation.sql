@@ -0,0 +1,3 @@
+-- Users of a super admin role are not limited to their own institution.
+ALTER TABLE role
+    ADD COLUMN is_super_admin TINYINT(1) NOT NULL DEFAULT 0 AFTER mfa_required;
@@ -0,0 +1,4 @@
+-- A role inherits the menu mappings of its parent and, through it, of every ancestor.
+ALTER TABLE role
+    ADD COLUMN parent_id VARCHAR(36) NULL AFTER role_desc,
+    ADD INDEX idx_role_parent_id (parent_id);
@@ -0,0 +1,12 @@
+-- Roles granted to a user on top of the primary role in users.role_id. A grant with an
+-- expires_at stops applying at that time and is cleaned up when the user is granted again.
+CREATE TABLE user_role (
+    id         VARCHAR(36)  NOT NULL PRIMARY KEY,
+    username   VARCHAR(200) NOT NULL,
+    role_id    VARCHAR(36)  NOT NULL,
+    expires_at DATETIME     NULL,
+    created_at DATETIME     NOT NULL,
+    created_by VARCHAR(200) NOT NULL,
+    UNIQUE KEY uk_user_role (username, role_id),
+    INDEX idx_user_role_role (role_id)
+);
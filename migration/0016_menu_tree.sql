@@ -0,0 +1,11 @@
+-- Menus nest under a parent and are ordered by sort_order within it for the navigation tree.
+-- Hidden menus are left out of the tree but their routes are still authorized as before.
+ALTER TABLE menu
+    ADD COLUMN parent_id  VARCHAR(36)  NULL AFTER menu_route,
+    ADD COLUMN sort_order INT          NOT NULL DEFAULT 0 AFTER parent_id,
+    ADD COLUMN icon       VARCHAR(100) NOT NULL DEFAULT '' AFTER sort_order,
+    ADD COLUMN is_hidden  TINYINT(1)   NOT NULL DEFAULT 0 AFTER icon,
+    ADD INDEX idx_menu_parent_id (parent_id);
+
+-- Mappings of menus deleted before DeleteMenu removed them grant nothing.
+DELETE FROM menu_mapping WHERE menu_id NOT IN (SELECT id FROM menu);
@@ -0,0 +1,8 @@
+-- Audit entries are scoped to the institution of the impersonated user, like login history.
+ALTER TABLE impersonation_audit
+    ADD COLUMN institution_id VARCHAR(200) NULL AFTER username,
+    ADD INDEX idx_impersonation_audit_institution (institution_id, created_at);
+
+UPDATE impersonation_audit a
+    JOIN users u ON u.username = a.username
+    SET a.institution_id = u.institution_id;